@@ -6,16 +6,26 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/getsentry/sentry-go"
+	"github.com/uswitch/hermod/pkg/github"
 	kubepkg "github.com/uswitch/hermod/pkg/kubernetes"
+	"github.com/uswitch/hermod/pkg/scm"
 	sentryClient "github.com/uswitch/hermod/pkg/sentry"
 	"github.com/uswitch/hermod/pkg/slack"
+	"github.com/uswitch/hermod/pkg/templates"
 	"gopkg.in/alecthomas/kingpin.v2"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 )
 
@@ -27,17 +37,65 @@ type options struct {
 	hermodGithubCommitSHAAnnotation string
 
 	githubAnnotationWarning bool
+
+	githubDeployments   bool
+	githubCommitStatus  bool
+	githubCheckRuns     bool
+	githubStatusContext string
+	githubCommitDetails bool
+	githubAPIURL        string
+
+	scmHosts map[string]string
+
+	autoRollbackLimit  int
+	autoRollbackWindow time.Duration
+
+	escalationDelay time.Duration
+
+	defaultSlackChannel string
+	namespaceSelector   string
+	excludeNamespaces   []string
+
+	maintenanceWindows []string
+	maintenanceSummary bool
+
+	digestThreshold int
+	digestWindow    time.Duration
+
+	groupReleases bool
+	releaseWindow time.Duration
+	helmReleases  bool
+
+	argoCDURL       string
+	argoCDNamespace string
+	gitOpsRevisions bool
+
+	configCRDs   bool
+	hermodConfig string
+
+	notifyDeployer          bool
+	deployerEmailAnnotation string
+
+	slackInteractionsAddress string
+	slackSocketMode          bool
+
+	templatesPath      string
+	templatesConfigMap string
+
+	configPath string
 }
 
 func main() {
 	log.Info("starting controller")
 	opts := &options{}
-	kingpin.Flag("kubeconfig", "Path to kubeconfig.").StringVar(&opts.kubeconfig)
-	kingpin.Flag("level", "Log level: debug, info, warn, error.").Default("info").EnumVar(&opts.logLevel, "debug", "info", "warn", "error")
-	kingpin.Flag("repo-url-annotation", "Annotation used to retrieve Github repository the deployment is from").Default("hermod.uswitch.com/gitrepo").StringVar(&opts.hermodGithubRepoAnnotation)
-	kingpin.Flag("commit-sha-annotation", "Annotation used to retrieve Git SHA responsible for the latest deployment").Default("hermod.uswitch.com/gitsha").StringVar(&opts.hermodGithubCommitSHAAnnotation)
-	kingpin.Flag("git-annotation-warning", "Warn for missing `repo-url-annotation` and `commit-sha-annotation values").BoolVar(&opts.githubAnnotationWarning)
-	kingpin.Parse()
+	app := newApp(opts)
+
+	// flags of the config file are parsed first so the command line takes precedence
+	configFile, args, err := loadConfigFile(os.Args[1:])
+	if err != nil {
+		log.Fatalf("error loading config file: %s", err)
+	}
+	kingpin.MustParse(app.Parse(args))
 
 	configureLogger(opts.logLevel)
 
@@ -45,6 +103,13 @@ func main() {
 
 	defer sentryClient.Cleanup()
 
+	if configFile != nil && configFile.HasSettings() && opts.configCRDs {
+		message := "only one of --config-crds and the settings of a config file can be used"
+		sentry.CaptureMessage(message)
+		sentryClient.Cleanup()
+		log.Fatalf(message)
+	}
+
 	kubeConfig, err := kubepkg.CreateClientConfig(opts.kubeconfig)
 	if err != nil {
 		message := fmt.Sprintf("error creating kube client config: %s", err)
@@ -61,6 +126,15 @@ func main() {
 		log.Fatalf(message)
 	}
 
+	// the dynamic client only reads custom resources when --gitops-revisions or --config-crds is set, which can be reloaded
+	dynamicClient, err := dynamic.NewForConfig(kubeConfig)
+	if err != nil {
+		message := fmt.Sprintf("Error building kubernetes dynamic client: %s", err.Error())
+		sentry.CaptureMessage(message)
+		sentryClient.Cleanup()
+		log.Fatalf(message)
+	}
+
 	slackClient, err := slack.NewClient()
 	if err != nil {
 		message := fmt.Sprintf("Error building slack client: %s", err.Error())
@@ -74,10 +148,70 @@ func main() {
 
 	ctx := context.Background()
 
-	watcher := kubepkg.NewDeploymentWatcher(kubeClient, opts.hermodGithubRepoAnnotation, opts.hermodGithubCommitSHAAnnotation, opts.githubAnnotationWarning)
+	settings, err := watcherSettings(ctx, opts, configFile, kubeClient)
+	if err != nil {
+		message := err.Error()
+		sentry.CaptureMessage(message)
+		sentryClient.Cleanup()
+		log.Fatalf(message)
+	}
+
+	watcher := kubepkg.NewDeploymentWatcher(kubeClient, settings)
 
 	watcher.Context = ctx
 	watcher.SlackClient = slackClient
+	watcher.DynamicClient = dynamicClient
+	watcher.Policies = kubepkg.PolicyOptions{
+		Watch:      opts.configCRDs,
+		ConfigName: opts.hermodConfig,
+	}
+	watcher.ConfigFile = opts.configPath
+	watcher.ReloadSettings = func(file *kubepkg.ConfigFile) (kubepkg.Settings, error) {
+		reloaded := &options{}
+		args, err := withFileFlags(file, os.Args[1:])
+		if err == nil {
+			_, err = newApp(reloaded).Parse(args)
+		}
+		if err != nil {
+			return kubepkg.Settings{}, err
+		}
+
+		for _, name := range restartFlags(opts, reloaded) {
+			log.Warnf("flag %s of config file %s changed, restart hermod to apply it", name, opts.configPath)
+		}
+		configureLogger(reloaded.logLevel)
+
+		return watcherSettings(ctx, reloaded, file, kubeClient)
+	}
+
+	interactions := slack.NewInteractions(slackClient, watcher)
+
+	if opts.slackInteractionsAddress != "" {
+		handler, err := interactions.HTTPHandler()
+		if err != nil {
+			message := fmt.Sprintf("Error building slack interactivity endpoint: %s", err.Error())
+			sentry.CaptureMessage(message)
+			sentryClient.Cleanup()
+			log.Fatalf(message)
+		}
+		watcher.SlackActions = true
+
+		go func() {
+			log.Infof("serving slack interactivity endpoint on %s", opts.slackInteractionsAddress)
+			log.Error(http.ListenAndServe(opts.slackInteractionsAddress, handler))
+		}()
+	}
+
+	if opts.slackSocketMode {
+		err := interactions.StartSocketMode(ctx)
+		if err != nil {
+			message := fmt.Sprintf("Error starting slack socket mode: %s", err.Error())
+			sentry.CaptureMessage(message)
+			sentryClient.Cleanup()
+			log.Fatalf(message)
+		}
+		watcher.SlackActions = true
+	}
 
 	http.Handle("/metrics", promhttp.Handler())
 	go http.ListenAndServe(":2112", nil)
@@ -86,3 +220,237 @@ func main() {
 
 	watcher.Run(ctx, stopCh)
 }
+
+// newApp defines the flags, parsing them into the options
+func newApp(opts *options) *kingpin.Application {
+	app := kingpin.New(filepath.Base(os.Args[0]), "")
+	app.Flag("kubeconfig", "Path to kubeconfig.").StringVar(&opts.kubeconfig)
+	app.Flag("level", "Log level: debug, info, warn, error.").Default("info").EnumVar(&opts.logLevel, "debug", "info", "warn", "error")
+	app.Flag("repo-url-annotation", "Annotation used to retrieve Github repository the deployment is from").Default("hermod.uswitch.com/gitrepo").StringVar(&opts.hermodGithubRepoAnnotation)
+	app.Flag("commit-sha-annotation", "Annotation used to retrieve Git SHA responsible for the latest deployment").Default("hermod.uswitch.com/gitsha").StringVar(&opts.hermodGithubCommitSHAAnnotation)
+	app.Flag("git-annotation-warning", "Warn for missing `repo-url-annotation` and `commit-sha-annotation values").BoolVar(&opts.githubAnnotationWarning)
+	app.Flag("github-deployments", "Report rollouts of deployments with `repo-url-annotation` and `commit-sha-annotation` as GitHub Deployments").BoolVar(&opts.githubDeployments)
+	app.Flag("github-commit-status", "Set a commit status on the `commit-sha-annotation` sha reflecting the rollout").BoolVar(&opts.githubCommitStatus)
+	app.Flag("github-check-runs", "Report a check run instead of a commit status, requires a GitHub App installation token").BoolVar(&opts.githubCheckRuns)
+	app.Flag("github-status-context", "Name of the commit status or check run, the cluster name is appended when set").Default("hermod").StringVar(&opts.githubStatusContext)
+	app.Flag("github-commit-details", "List the commits between the previously successful and the new sha in notifications").BoolVar(&opts.githubCommitDetails)
+	app.Flag("github-api-url", "Base URL of the GitHub API, change for GitHub Enterprise").Default(github.DefaultBaseURL).StringVar(&opts.githubAPIURL)
+	app.Flag("scm-host", "Source control provider for a self-hosted repository host, e.g. git.example.com=gitlab. Can be repeated.").StringMapVar(&opts.scmHosts)
+	app.Flag("auto-rollback-limit", "Maximum number of automatic rollbacks of a deployment within `auto-rollback-window`").Default("1").IntVar(&opts.autoRollbackLimit)
+	app.Flag("auto-rollback-window", "Period over which `auto-rollback-limit` applies").Default("1h").DurationVar(&opts.autoRollbackWindow)
+	app.Flag("default-slack-channel", "Slack channel of namespaces labelled hermod.uswitch.com/enabled=true without a hermod.uswitch.com/slack annotation").StringVar(&opts.defaultSlackChannel)
+	app.Flag("namespace-selector", "Only track namespaces matching this label selector, e.g. team=payments. Matching namespaces without a channel post to default-slack-channel.").StringVar(&opts.namespaceSelector)
+	app.Flag("exclude-namespace", "Never track namespaces matching this pattern, e.g. kube-* or *-preview. Can be repeated.").StringsVar(&opts.excludeNamespaces)
+	app.Flag("maintenance-window", "Cluster-wide maintenance window during which only failures are notified, a cron schedule followed by its duration, e.g. \"0 2 * * SAT 4h\". Can be repeated.").StringsVar(&opts.maintenanceWindows)
+	app.Flag("maintenance-summary", "Summarise the messages muted during a maintenance window at its end").BoolVar(&opts.maintenanceSummary)
+	app.Flag("digest-threshold", "Collapse rollouts into one digest message once a channel gets this many rollout messages within `digest-window`, 0 disables digests").Default("0").IntVar(&opts.digestThreshold)
+	app.Flag("digest-window", "Period over which `digest-threshold` applies, a digest ends once a channel had no rollouts for this long").Default("2m").DurationVar(&opts.digestWindow)
+	app.Flag("group-releases", "Report the rollouts of deployments in a namespace sharing a release key in one message, see README").BoolVar(&opts.groupReleases)
+	app.Flag("release-window", "How long a release is tracked after its last rollout, later rollouts start a new release").Default("10m").DurationVar(&opts.releaseWindow)
+	app.Flag("helm-releases", "Read the release secrets of deployments installed by Helm to report their release, chart and app version, requires permission to list secrets").BoolVar(&opts.helmReleases)
+	app.Flag("argocd-url", "Base URL of the Argo CD UI, e.g. https://argocd.example.com, to link to the applications of deployments").StringVar(&opts.argoCDURL)
+	app.Flag("argocd-namespace", "Namespace of the Argo CD applications named by the argocd.argoproj.io/instance label").Default("argocd").StringVar(&opts.argoCDNamespace)
+	app.Flag("gitops-revisions", "Read the repository and revision of deployments without `repo-url-annotation` and `commit-sha-annotation` from their Argo CD Application or Flux Kustomization").BoolVar(&opts.gitOpsRevisions)
+	app.Flag("config-crds", "Watch the HermodConfig and NotificationPolicy custom resources for configuration, their definitions must be installed").BoolVar(&opts.configCRDs)
+	app.Flag("hermod-config", "Name of the HermodConfig to apply when `config-crds` is set").Default("default").StringVar(&opts.hermodConfig)
+	app.Flag("escalation-delay", "How long a rollout has to keep failing before the escalation owners of the deployment are mentioned, 0 disables escalation").Default("30m").DurationVar(&opts.escalationDelay)
+	app.Flag("notify-deployer", "Send failure messages directly to the deployer, found by `deployer-email-annotation` or the author of the deployed commit").BoolVar(&opts.notifyDeployer)
+	app.Flag("deployer-email-annotation", "Annotation holding the email of whoever deployed the deployment").Default("hermod.uswitch.com/deployer-email").StringVar(&opts.deployerEmailAnnotation)
+	app.Flag("slack-interactions-address", "Address to serve the Slack interactivity and slash command endpoints on, e.g. :8080. Enables buttons on failure messages.").StringVar(&opts.slackInteractionsAddress)
+	app.Flag("slack-socket-mode", "Receive Slack button clicks and slash commands over Socket Mode, requires SLACK_APP_TOKEN. Enables buttons on failure messages.").BoolVar(&opts.slackSocketMode)
+	app.Flag("templates", "Path to a file or directory of message templates, see README.").StringVar(&opts.templatesPath)
+	app.Flag("templates-configmap", "ConfigMap holding message templates, as <namespace>/<name>.").StringVar(&opts.templatesConfigMap)
+	app.Flag("config", "Path to a YAML config file of flags, routing, sinks and templates, reloaded when it changes, see README.").StringVar(&opts.configPath)
+
+	return app
+}
+
+// restartFlags are the flags which changed but are only read at startup
+func restartFlags(old, new *options) []string {
+	var changed []string
+	for name, differ := range map[string]bool{
+		"kubeconfig":                 old.kubeconfig != new.kubeconfig,
+		"config-crds":                old.configCRDs != new.configCRDs,
+		"hermod-config":              old.hermodConfig != new.hermodConfig,
+		"slack-interactions-address": old.slackInteractionsAddress != new.slackInteractionsAddress,
+		"slack-socket-mode":          old.slackSocketMode != new.slackSocketMode,
+		"config":                     old.configPath != new.configPath,
+	} {
+		if differ {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+
+	return changed
+}
+
+// watcherSettings builds the settings of the deployment watcher from the flags, at startup and whenever the flags of the config file change
+func watcherSettings(ctx context.Context, opts *options, configFile *kubepkg.ConfigFile, kubeClient kubernetes.Interface) (kubepkg.Settings, error) {
+	scmResolver, err := scm.NewResolver(opts.scmHosts)
+	if err != nil {
+		return kubepkg.Settings{}, fmt.Errorf("error configuring scm providers: %s", err)
+	}
+
+	var githubClient *github.Client
+	// the HermodConfig or the config file can enable GitHub reporting later, given a token
+	configured := opts.configCRDs || (configFile != nil && configFile.HasSettings())
+	if opts.githubDeployments || opts.githubCommitStatus || opts.githubCheckRuns || opts.githubCommitDetails || (configured && os.Getenv("GITHUB_TOKEN") != "") {
+		githubClient, err = github.NewClient(opts.githubAPIURL)
+		if err != nil {
+			return kubepkg.Settings{}, fmt.Errorf("error building github client: %s", err)
+		}
+	}
+
+	namespaceSelector, err := labels.Parse(opts.namespaceSelector)
+	if err != nil {
+		return kubepkg.Settings{}, fmt.Errorf("error parsing namespace selector: %s", err)
+	}
+
+	// matching namespaces without a channel of their own post to the default channel, without one they are not tracked at all
+	if !namespaceSelector.Empty() && opts.defaultSlackChannel == "" && (configFile == nil || configFile.DefaultSlackChannel() == "") {
+		if opts.configCRDs {
+			log.Warnf("--namespace-selector is set without --default-slack-channel, matching namespaces without a hermod.uswitch.com/slack annotation are not tracked unless the HermodConfig sets slack.defaultChannel")
+		} else {
+			log.Warnf("--namespace-selector is set without --default-slack-channel, matching namespaces without a hermod.uswitch.com/slack annotation are not tracked")
+		}
+	}
+
+	for _, pattern := range opts.excludeNamespaces {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return kubepkg.Settings{}, fmt.Errorf("error parsing exclude namespace pattern %q: %s", pattern, err)
+		}
+	}
+
+	var maintenanceWindows []kubepkg.MaintenanceWindow
+	for _, value := range opts.maintenanceWindows {
+		window, err := kubepkg.ParseMaintenanceWindow(value)
+		if err != nil {
+			return kubepkg.Settings{}, fmt.Errorf("error parsing maintenance window: %s", err)
+		}
+		maintenanceWindows = append(maintenanceWindows, window)
+	}
+
+	messageTemplates, err := loadTemplates(ctx, kubeClient, opts.templatesPath, opts.templatesConfigMap)
+	if err != nil {
+		return kubepkg.Settings{}, fmt.Errorf("error loading message templates: %s", err)
+	}
+
+	return kubepkg.Settings{
+		GithubClient: githubClient,
+		GithubOptions: kubepkg.GithubOptions{
+			Deployments:   opts.githubDeployments,
+			CommitStatus:  opts.githubCommitStatus,
+			CheckRuns:     opts.githubCheckRuns,
+			CommitDetails: opts.githubCommitDetails,
+			StatusContext: opts.githubStatusContext,
+		},
+		SCMResolver:         scmResolver,
+		Templates:           messageTemplates,
+		DefaultSlackChannel: opts.defaultSlackChannel,
+		NamespaceSelector:   namespaceSelector,
+		ExcludeNamespaces:   opts.excludeNamespaces,
+		MaintenanceWindows:  maintenanceWindows,
+		MaintenanceSummary:  opts.maintenanceSummary,
+		Digest: kubepkg.DigestOptions{
+			Threshold: opts.digestThreshold,
+			Window:    opts.digestWindow,
+		},
+		Releases: kubepkg.ReleaseOptions{
+			Group:  opts.groupReleases,
+			Window: opts.releaseWindow,
+		},
+		HelmReleases: opts.helmReleases,
+		GitOps: kubepkg.GitOpsOptions{
+			ArgoCDURL:       opts.argoCDURL,
+			ArgoCDNamespace: opts.argoCDNamespace,
+			Revisions:       opts.gitOpsRevisions,
+		},
+		AutoRollback: kubepkg.AutoRollbackOptions{
+			Limit:  opts.autoRollbackLimit,
+			Window: opts.autoRollbackWindow,
+		},
+		EscalationDelay:                 opts.escalationDelay,
+		NotifyDeployer:                  opts.notifyDeployer,
+		HermodGithubRepoAnnotation:      opts.hermodGithubRepoAnnotation,
+		HermodGithubCommitSHAAnnotation: opts.hermodGithubCommitSHAAnnotation,
+		GithubAnnotationWarning:         opts.githubAnnotationWarning,
+		DeployerEmailAnnotation:         opts.deployerEmailAnnotation,
+	}, nil
+}
+
+// loadTemplates loads the message templates from a path or a ConfigMap, there are none when neither is set
+func loadTemplates(ctx context.Context, client kubernetes.Interface, path, configMap string) (*templates.Templates, error) {
+	switch {
+	case path != "" && configMap != "":
+		return nil, fmt.Errorf("only one of --templates and --templates-configmap can be set")
+	case path != "":
+		return templates.Load(path)
+	case configMap != "":
+		parts := strings.Split(configMap, "/")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("%q is not in the form <namespace>/<name>", configMap)
+		}
+		return templates.LoadConfigMap(ctx, client, parts[0], parts[1])
+	}
+
+	return nil, nil
+}
+
+// loadConfigFile loads the config file given by --config, if any, and prepends its flags to the command line arguments
+func loadConfigFile(args []string) (*kubepkg.ConfigFile, []string, error) {
+	var path string
+	for i, arg := range args {
+		switch {
+		case strings.HasPrefix(arg, "--config="):
+			path = strings.TrimPrefix(arg, "--config=")
+		case arg == "--config" && i+1 < len(args):
+			path = args[i+1]
+		}
+	}
+	if path == "" {
+		return nil, args, nil
+	}
+
+	file, err := kubepkg.LoadConfigFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	merged, err := withFileFlags(file, args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return file, merged, nil
+}
+
+// withFileFlags prepends the flags of the config file to the command line arguments, leaving out those also given on the command line
+func withFileFlags(file *kubepkg.ConfigFile, args []string) ([]string, error) {
+	given := map[string]bool{}
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "--") {
+			given[flagName(arg)] = true
+		}
+	}
+
+	fileArgs, err := file.FlagArgs()
+	if err != nil {
+		return nil, err
+	}
+
+	var merged []string
+	for _, arg := range fileArgs {
+		if !given[flagName(arg)] {
+			merged = append(merged, arg)
+		}
+	}
+
+	return append(merged, args...), nil
+}
+
+// flagName is the name of the flag of an argument, e.g. level for --level=debug and notify-deployer for --no-notify-deployer
+func flagName(arg string) string {
+	name := strings.SplitN(strings.TrimPrefix(arg, "--"), "=", 2)[0]
+	return strings.TrimPrefix(name, "no-")
+}
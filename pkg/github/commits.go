@@ -0,0 +1,80 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Commit is the summary of a single commit
+type Commit struct {
+	SHA     string
+	Message string
+	Author  string
+}
+
+type compareResponse struct {
+	Commits []struct {
+		SHA    string `json:"sha"`
+		Commit struct {
+			Message string `json:"message"`
+			Author  struct {
+				Name string `json:"name"`
+			} `json:"author"`
+		} `json:"commit"`
+		Author *struct {
+			Login string `json:"login"`
+		} `json:"author"`
+	} `json:"commits"`
+}
+
+// CompareCommits lists the commits reachable from head but not from base, oldest first
+func (c *Client) CompareCommits(ctx context.Context, repo Repository, base, head string) ([]Commit, error) {
+	log.Debugf("comparing %s/%s %s...%s", repo.Owner, repo.Name, base, head)
+
+	var response compareResponse
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/repos/%s/%s/compare/%s...%s", repo.Owner, repo.Name, base, head), nil, &response)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare %s...%s of %s/%s: %v", base, head, repo.Owner, repo.Name, err)
+	}
+
+	var commits []Commit
+	for _, c := range response.Commits {
+		commit := Commit{
+			SHA: c.SHA,
+			// only the subject line of the commit message
+			Message: strings.SplitN(c.Commit.Message, "\n", 2)[0],
+			Author:  c.Commit.Author.Name,
+		}
+		if c.Author != nil && c.Author.Login != "" {
+			commit.Author = c.Author.Login
+		}
+		commits = append(commits, commit)
+	}
+
+	return commits, nil
+}
+
+type commitResponse struct {
+	Commit struct {
+		Author struct {
+			Email string `json:"email"`
+		} `json:"author"`
+	} `json:"commit"`
+}
+
+// CommitAuthorEmail returns the git author email of a commit
+func (c *Client) CommitAuthorEmail(ctx context.Context, repo Repository, sha string) (string, error) {
+	log.Debugf("getting author of %s/%s %s", repo.Owner, repo.Name, sha)
+
+	var response commitResponse
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/repos/%s/%s/commits/%s", repo.Owner, repo.Name, sha), nil, &response)
+	if err != nil {
+		return "", fmt.Errorf("failed to get commit %s of %s/%s: %v", sha, repo.Owner, repo.Name, err)
+	}
+
+	return response.Commit.Author.Email, nil
+}
@@ -0,0 +1,176 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/uswitch/hermod/pkg/text"
+)
+
+const (
+	DefaultBaseURL = "https://api.github.com"
+
+	tokenEnv = "GITHUB_TOKEN"
+)
+
+// deployment status states
+const (
+	StateInProgress = "in_progress"
+	StateSuccess    = "success"
+	StateFailure    = "failure"
+)
+
+// Client calls the GitHub REST API to report rollouts and read the commits being deployed
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// Repository identifies a GitHub repository by owner and name
+type Repository struct {
+	Owner string
+	Name  string
+}
+
+// NewClient instantiates the expected GitHub Client struct fields
+func NewClient(baseURL string) (*Client, error) {
+	token := os.Getenv(tokenEnv)
+	if token == "" {
+		return nil, fmt.Errorf("%s environment variable not set", tokenEnv)
+	}
+
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+
+	return &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		token:      token,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// ParseRepository extracts the owner and name from a repository url such as https://github.com/my-org/my-app
+func ParseRepository(repoURL string) (Repository, error) {
+	u, err := url.Parse(repoURL)
+	if err != nil {
+		return Repository{}, fmt.Errorf("failed to parse repository url %q: %v", repoURL, err)
+	}
+
+	parts := strings.Split(strings.TrimSuffix(strings.Trim(u.Path, "/"), ".git"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return Repository{}, fmt.Errorf("repository url %q is not in the form <host>/<owner>/<repo>", repoURL)
+	}
+
+	return Repository{Owner: parts[0], Name: parts[1]}, nil
+}
+
+type deploymentRequest struct {
+	Ref              string   `json:"ref"`
+	Environment      string   `json:"environment"`
+	Description      string   `json:"description"`
+	AutoMerge        bool     `json:"auto_merge"`
+	RequiredContexts []string `json:"required_contexts"`
+}
+
+type deploymentResponse struct {
+	ID int64 `json:"id"`
+}
+
+// CreateDeployment creates a GitHub deployment for the given sha and returns its id
+func (c *Client) CreateDeployment(ctx context.Context, repo Repository, sha, environment, description string) (int64, error) {
+	log.Debugf("creating github deployment for %s/%s@%s in '%s'", repo.Owner, repo.Name, sha, environment)
+
+	request := deploymentRequest{
+		Ref:         sha,
+		Environment: environment,
+		Description: description,
+		// the rollout is already happening, github should not block it on merges or status checks
+		AutoMerge:        false,
+		RequiredContexts: []string{},
+	}
+
+	var response deploymentResponse
+	err := c.do(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/%s/deployments", repo.Owner, repo.Name), request, &response)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create deployment for %s/%s@%s: %v", repo.Owner, repo.Name, sha, err)
+	}
+
+	return response.ID, nil
+}
+
+type deploymentStatusRequest struct {
+	State        string `json:"state"`
+	Description  string `json:"description,omitempty"`
+	AutoInactive bool   `json:"auto_inactive"`
+}
+
+// CreateDeploymentStatus reports the state of a previously created GitHub deployment
+func (c *Client) CreateDeploymentStatus(ctx context.Context, repo Repository, deploymentID int64, state, description string) error {
+	log.Debugf("setting github deployment %d for %s/%s to '%s'", deploymentID, repo.Owner, repo.Name, state)
+
+	request := deploymentStatusRequest{
+		State:        state,
+		Description:  text.Truncate(description, 140),
+		AutoInactive: state == StateSuccess,
+	}
+
+	err := c.do(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/%s/deployments/%d/statuses", repo.Owner, repo.Name, deploymentID), request, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create status '%s' for deployment %d of %s/%s: %v", state, deploymentID, repo.Owner, repo.Name, err)
+	}
+
+	return nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, into interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		marshalledBody, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("error marshalling data to json: %v", err)
+		}
+		reader = bytes.NewReader(marshalledBody)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	if into == nil {
+		return nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(into); err != nil {
+		return fmt.Errorf("error decoding response: %v", err)
+	}
+
+	return nil
+}
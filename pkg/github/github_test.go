@@ -0,0 +1,215 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	return &Client{baseURL: server.URL, token: "token", httpClient: server.Client()}
+}
+
+func TestParseRepository(t *testing.T) {
+	tests := []struct {
+		name           string
+		repoURL        string
+		expectedOutput Repository
+		expectError    bool
+	}{
+		{
+			name:           "github url",
+			repoURL:        "https://github.com/my-org/my-app",
+			expectedOutput: Repository{Owner: "my-org", Name: "my-app"},
+		},
+		{
+			name:           "trailing slash and .git suffix",
+			repoURL:        "https://github.example.com/my-org/my-app.git/",
+			expectedOutput: Repository{Owner: "my-org", Name: "my-app"},
+		},
+		{
+			name:        "missing repository name",
+			repoURL:     "https://github.com/my-org",
+			expectError: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := ParseRepository(tt.repoURL)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("ParseRepository() error = %v, expectError %v", err, tt.expectError)
+			}
+			if !reflect.DeepEqual(output, tt.expectedOutput) {
+				t.Errorf("ParseRepository() = %v, expectedOutput %v", output, tt.expectedOutput)
+			}
+		})
+	}
+}
+
+func TestCreateDeployment(t *testing.T) {
+	var received deploymentRequest
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/repos/my-org/my-app/deployments" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("unexpected authorization header %q", r.Header.Get("Authorization"))
+		}
+		json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": 42}`))
+	})
+
+	id, err := client.CreateDeployment(context.Background(), Repository{Owner: "my-org", Name: "my-app"}, "abc123", "cluster/ns", "rolling out")
+	if err != nil {
+		t.Fatalf("CreateDeployment() error = %v", err)
+	}
+	if id != 42 {
+		t.Errorf("CreateDeployment() = %v, expectedOutput %v", id, 42)
+	}
+
+	expectedRequest := deploymentRequest{Ref: "abc123", Environment: "cluster/ns", Description: "rolling out", RequiredContexts: []string{}}
+	if !reflect.DeepEqual(received, expectedRequest) {
+		t.Errorf("CreateDeployment() sent %v, expected %v", received, expectedRequest)
+	}
+}
+
+func TestCreateDeploymentStatus(t *testing.T) {
+	var received deploymentStatusRequest
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/repos/my-org/my-app/deployments/42/statuses" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	err := client.CreateDeploymentStatus(context.Background(), Repository{Owner: "my-org", Name: "my-app"}, 42, StateSuccess, "done")
+	if err != nil {
+		t.Fatalf("CreateDeploymentStatus() error = %v", err)
+	}
+
+	expectedRequest := deploymentStatusRequest{State: StateSuccess, Description: "done", AutoInactive: true}
+	if !reflect.DeepEqual(received, expectedRequest) {
+		t.Errorf("CreateDeploymentStatus() sent %v, expected %v", received, expectedRequest)
+	}
+}
+
+func TestErrorResponse(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message": "Not Found"}`))
+	})
+
+	_, err := client.CreateDeployment(context.Background(), Repository{Owner: "my-org", Name: "my-app"}, "abc123", "ns", "")
+	if err == nil {
+		t.Errorf("CreateDeployment() expected an error for a 404 response")
+	}
+}
+
+func TestCreateCommitStatus(t *testing.T) {
+	var received commitStatusRequest
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/repos/my-org/my-app/statuses/abc123" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	err := client.CreateCommitStatus(context.Background(), Repository{Owner: "my-org", Name: "my-app"}, "abc123", StatusPending, "hermod/prod-cluster", "rolling out")
+	if err != nil {
+		t.Fatalf("CreateCommitStatus() error = %v", err)
+	}
+
+	expectedRequest := commitStatusRequest{State: StatusPending, Context: "hermod/prod-cluster", Description: "rolling out"}
+	if !reflect.DeepEqual(received, expectedRequest) {
+		t.Errorf("CreateCommitStatus() sent %v, expected %v", received, expectedRequest)
+	}
+}
+
+func TestCheckRun(t *testing.T) {
+	var received []checkRunRequest
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var request checkRunRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		received = append(received, request)
+
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/repos/my-org/my-app/check-runs":
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id": 7}`))
+		case r.Method == http.MethodPatch && r.URL.Path == "/repos/my-org/my-app/check-runs/7":
+			w.WriteHeader(http.StatusOK)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	})
+
+	repo := Repository{Owner: "my-org", Name: "my-app"}
+	id, err := client.CreateCheckRun(context.Background(), repo, "abc123", "hermod", CheckRunOutput{Title: "started", Summary: "rolling out"})
+	if err != nil {
+		t.Fatalf("CreateCheckRun() error = %v", err)
+	}
+
+	err = client.CompleteCheckRun(context.Background(), repo, id, ConclusionFailure, CheckRunOutput{Title: "failed", Summary: "ImagePullBackOff"})
+	if err != nil {
+		t.Fatalf("CompleteCheckRun() error = %v", err)
+	}
+
+	expectedRequests := []checkRunRequest{
+		{Name: "hermod", HeadSHA: "abc123", Status: CheckRunInProgress, Output: &CheckRunOutput{Title: "started", Summary: "rolling out"}},
+		{Status: CheckRunCompleted, Conclusion: ConclusionFailure, Output: &CheckRunOutput{Title: "failed", Summary: "ImagePullBackOff"}},
+	}
+	if !reflect.DeepEqual(received, expectedRequests) {
+		t.Errorf("check run requests = %v, expected %v", received, expectedRequests)
+	}
+}
+
+func TestCompareCommits(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/repos/my-org/my-app/compare/old...new" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		w.Write([]byte(`{"commits": [
+			{"sha": "aaa", "commit": {"message": "Fix the thing\n\nLonger description", "author": {"name": "Jane Doe"}}, "author": {"login": "jdoe"}},
+			{"sha": "bbb", "commit": {"message": "Bump version", "author": {"name": "Build Bot"}}, "author": null}
+		]}`))
+	})
+
+	commits, err := client.CompareCommits(context.Background(), Repository{Owner: "my-org", Name: "my-app"}, "old", "new")
+	if err != nil {
+		t.Fatalf("CompareCommits() error = %v", err)
+	}
+
+	expectedOutput := []Commit{
+		{SHA: "aaa", Message: "Fix the thing", Author: "jdoe"},
+		{SHA: "bbb", Message: "Bump version", Author: "Build Bot"},
+	}
+	if !reflect.DeepEqual(commits, expectedOutput) {
+		t.Errorf("CompareCommits() = %v, expectedOutput %v", commits, expectedOutput)
+	}
+}
+
+func TestCommitAuthorEmail(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/repos/my-org/my-app/commits/abc" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		w.Write([]byte(`{"sha": "abc", "commit": {"author": {"name": "Jane Doe", "email": "jane@example.com"}}}`))
+	})
+
+	email, err := client.CommitAuthorEmail(context.Background(), Repository{Owner: "my-org", Name: "my-app"}, "abc")
+	if err != nil {
+		t.Fatalf("CommitAuthorEmail() error = %v", err)
+	}
+	if email != "jane@example.com" {
+		t.Errorf("CommitAuthorEmail() = %v, expectedOutput %v", email, "jane@example.com")
+	}
+}
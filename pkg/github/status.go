@@ -0,0 +1,115 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/uswitch/hermod/pkg/text"
+)
+
+// commit status states
+const (
+	StatusPending = "pending"
+	StatusSuccess = "success"
+	StatusFailure = "failure"
+)
+
+// check run statuses and conclusions
+const (
+	CheckRunInProgress = "in_progress"
+	CheckRunCompleted  = "completed"
+
+	ConclusionSuccess = "success"
+	ConclusionFailure = "failure"
+)
+
+// GitHub rejects check run summaries longer than this
+const checkRunSummaryLimit = 65535
+
+type commitStatusRequest struct {
+	State       string `json:"state"`
+	Context     string `json:"context"`
+	Description string `json:"description,omitempty"`
+}
+
+// CreateCommitStatus sets the status for the given context on a commit
+func (c *Client) CreateCommitStatus(ctx context.Context, repo Repository, sha, state, statusContext, description string) error {
+	log.Debugf("setting github status '%s' for %s/%s@%s to '%s'", statusContext, repo.Owner, repo.Name, sha, state)
+
+	request := commitStatusRequest{
+		State:       state,
+		Context:     statusContext,
+		Description: text.Truncate(description, 140),
+	}
+
+	err := c.do(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/%s/statuses/%s", repo.Owner, repo.Name, sha), request, nil)
+	if err != nil {
+		return fmt.Errorf("failed to set status '%s' for %s/%s@%s: %v", statusContext, repo.Owner, repo.Name, sha, err)
+	}
+
+	return nil
+}
+
+// CheckRunOutput is the title and markdown summary shown on a check run
+type CheckRunOutput struct {
+	Title   string `json:"title"`
+	Summary string `json:"summary"`
+}
+
+type checkRunRequest struct {
+	Name       string          `json:"name,omitempty"`
+	HeadSHA    string          `json:"head_sha,omitempty"`
+	Status     string          `json:"status"`
+	Conclusion string          `json:"conclusion,omitempty"`
+	Output     *CheckRunOutput `json:"output,omitempty"`
+}
+
+type checkRunResponse struct {
+	ID int64 `json:"id"`
+}
+
+// CreateCheckRun starts a check run with the given name on a commit and returns its id.
+// Check runs can only be created with a GitHub App installation token.
+func (c *Client) CreateCheckRun(ctx context.Context, repo Repository, sha, name string, output CheckRunOutput) (int64, error) {
+	log.Debugf("creating github check run '%s' for %s/%s@%s", name, repo.Owner, repo.Name, sha)
+
+	request := checkRunRequest{
+		Name:    name,
+		HeadSHA: sha,
+		Status:  CheckRunInProgress,
+		Output:  limitOutput(output),
+	}
+
+	var response checkRunResponse
+	err := c.do(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/%s/check-runs", repo.Owner, repo.Name), request, &response)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create check run '%s' for %s/%s@%s: %v", name, repo.Owner, repo.Name, sha, err)
+	}
+
+	return response.ID, nil
+}
+
+// CompleteCheckRun marks a previously created check run as completed with the given conclusion
+func (c *Client) CompleteCheckRun(ctx context.Context, repo Repository, checkRunID int64, conclusion string, output CheckRunOutput) error {
+	log.Debugf("completing github check run %d for %s/%s with '%s'", checkRunID, repo.Owner, repo.Name, conclusion)
+
+	request := checkRunRequest{
+		Status:     CheckRunCompleted,
+		Conclusion: conclusion,
+		Output:     limitOutput(output),
+	}
+
+	err := c.do(ctx, http.MethodPatch, fmt.Sprintf("/repos/%s/%s/check-runs/%d", repo.Owner, repo.Name, checkRunID), request, nil)
+	if err != nil {
+		return fmt.Errorf("failed to complete check run %d of %s/%s: %v", checkRunID, repo.Owner, repo.Name, err)
+	}
+
+	return nil
+}
+
+func limitOutput(output CheckRunOutput) *CheckRunOutput {
+	output.Summary = text.Truncate(output.Summary, checkRunSummaryLimit)
+	return &output
+}
@@ -0,0 +1,84 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	hermodSlackAllowedUsersAnnotation = "hermod.uswitch.com/slack-allowed-users"
+)
+
+// AllowedSlackUsers will return the Slack user ids allowed to act on deployments in the namespace
+func (b *deploymentInformer) AllowedSlackUsers(namespace string) ([]string, error) {
+	if b.namespaceIndexer == nil {
+		return nil, fmt.Errorf("namespace cache has not synced yet")
+	}
+
+	nsResource, exists, err := b.namespaceIndexer.GetByKey(namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get namespace from cache: %s", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("namespace %s not found", namespace)
+	}
+
+	nsAnnotations, err := meta.NewAccessor().Annotations(nsResource.(runtime.Object))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get annotations from namespace: %s", err)
+	}
+
+	return splitList(nsAnnotations[hermodSlackAllowedUsersAnnotation]), nil
+}
+
+// RollbackDeployment will roll the deployment back to the last revision recorded as `pass`
+func (b *deploymentInformer) RollbackDeployment(ctx context.Context, namespace, name string) (string, error) {
+	deployment, err := b.getDeployment(namespace, name)
+	if err != nil {
+		return "", err
+	}
+
+	target := deployment.Annotations[hermodLastSuccessfulRevisionAnnotation]
+	if target == "" {
+		return "", fmt.Errorf("no revision has been recorded as successful yet")
+	}
+	if target == deployment.Annotations[revision] {
+		return "", fmt.Errorf("revision %s is already the last successful revision", target)
+	}
+
+	err = rollbackDeployment(ctx, b.client, deployment, target)
+	if err != nil {
+		return "", err
+	}
+
+	return "revision " + target, nil
+}
+
+// PauseDeployment will pause the rollout of the deployment
+func (b *deploymentInformer) PauseDeployment(ctx context.Context, namespace, name string) error {
+	_, err := b.client.AppsV1().Deployments(namespace).Patch(ctx, name, types.MergePatchType, []byte(`{"spec":{"paused":true}}`), metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to pause deployment: %v", err)
+	}
+
+	return nil
+}
+
+// getDeployment will return the deployment from the informer cache
+func (b *deploymentInformer) getDeployment(namespace, name string) (*appsv1.Deployment, error) {
+	obj, exists, err := b.store.GetByKey(fmt.Sprintf("%s/%s", namespace, name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deployment from cache: %s", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("deployment %s/%s not found", namespace, name)
+	}
+
+	return obj.(*appsv1.Deployment), nil
+}
@@ -0,0 +1,107 @@
+package kubernetes
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/uswitch/hermod/pkg/templates"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/client-go/kubernetes"
+)
+
+// alert levels of the `hermod.uswitch.com/alert` annotation
+const (
+	hermodAlertAll         = "all"
+	hermodAlertResult      = "result"
+	hermodAlertFailure     = "failure"
+	hermodAlertNone        = "none"
+	hermodAlertChangesOnly = "changes-only"
+
+	// set on the pod template by `kubectl rollout restart`
+	restartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+)
+
+// alertOutcomes are the outcomes each alert level sends messages for
+var alertOutcomes = map[string][]string{
+	hermodAlertAll:         {templates.PhaseStarted, templates.PhaseSucceeded, templates.PhaseFailed},
+	hermodAlertResult:      {templates.PhaseSucceeded, templates.PhaseFailed},
+	hermodAlertFailure:     {templates.PhaseFailed},
+	hermodAlertNone:        {},
+	hermodAlertChangesOnly: {templates.PhaseStarted, templates.PhaseSucceeded, templates.PhaseFailed},
+}
+
+// validAlertLevel will return the alert level, warning about unknown levels and treating them as `all`
+func validAlertLevel(deployment *appsv1.Deployment, level string) string {
+	if level == "" {
+		return hermodAlertAll
+	}
+	if _, ok := alertOutcomes[level]; !ok {
+		log.Warnf("unknown alert level %q for deployment `%s` in `%s` namespace, expected one of all, result, failure, none or changes-only", level, deployment.Name, deployment.Namespace)
+		return hermodAlertAll
+	}
+
+	return level
+}
+
+// alertLevel will return the alert level of the deployment, from its annotations or those of its namespace
+func (b *deploymentInformer) alertLevel(deployment *appsv1.Deployment) (string, error) {
+	level, err := b.getSetting(deployment, hermodAlertAnnotation)
+	if err != nil {
+		return "", err
+	}
+
+	return validAlertLevel(deployment, level), nil
+}
+
+// shouldAlert will tell whether a message about the outcome is sent at the alert level.
+// At `changes-only` rollouts that only restarted the pods are not reported unless they fail.
+func (b *deploymentInformer) shouldAlert(level, outcome string, deployment *appsv1.Deployment) bool {
+	sent := false
+	for _, o := range alertOutcomes[level] {
+		sent = sent || o == outcome
+	}
+
+	if sent && level == hermodAlertChangesOnly && outcome != templates.PhaseFailed {
+		return !isRestartRollout(b.Context, b.client, deployment)
+	}
+
+	return sent
+}
+
+// isRestartRollout will tell whether the only change in the rollout is a `kubectl rollout restart`
+func isRestartRollout(ctx context.Context, client kubernetes.Interface, deployment *appsv1.Deployment) bool {
+	replicaSets, err := getDeploymentReplicaSets(ctx, client, deployment)
+	if err != nil {
+		log.Warnf("failed to get the replicasets of deployment `%s` in `%s` namespace: %v", deployment.Name, deployment.Namespace, err)
+		return false
+	}
+
+	currentRS, previousRS := currentAndPreviousReplicaSets(replicaSets, deployment.Annotations[revision])
+	if previousRS == nil {
+		return false
+	}
+
+	currentTemplate := deployment.Spec.Template
+	if currentRS != nil {
+		currentTemplate = currentRS.Spec.Template
+	}
+
+	return onlyRestarted(previousRS.Spec.Template, currentTemplate)
+}
+
+// onlyRestarted will tell whether two pod templates only differ in their restart annotation
+func onlyRestarted(old, new corev1.PodTemplateSpec) bool {
+	if old.Annotations[restartedAtAnnotation] == new.Annotations[restartedAtAnnotation] {
+		return false
+	}
+
+	old, new = *old.DeepCopy(), *new.DeepCopy()
+	for _, template := range []*corev1.PodTemplateSpec{&old, &new} {
+		delete(template.Annotations, restartedAtAnnotation)
+		delete(template.Labels, appsv1.DefaultDeploymentUniqueLabelKey)
+	}
+
+	return apiequality.Semantic.DeepEqual(old, new)
+}
@@ -0,0 +1,107 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestOnlyRestarted(t *testing.T) {
+	template := func(image, restartedAt, hash string) corev1.PodTemplateSpec {
+		return corev1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels:      map[string]string{"app": "api", appsv1.DefaultDeploymentUniqueLabelKey: hash},
+				Annotations: map[string]string{restartedAtAnnotation: restartedAt},
+			},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "api", Image: image}}},
+		}
+	}
+	tests := []struct {
+		name           string
+		old            corev1.PodTemplateSpec
+		new            corev1.PodTemplateSpec
+		expectedOutput bool
+	}{
+		{
+			name:           "restart",
+			old:            template("api:v1", "", "aaa"),
+			new:            template("api:v1", "2021-06-01T12:00:00Z", "bbb"),
+			expectedOutput: true,
+		},
+		{
+			name: "restart with new image",
+			old:  template("api:v1", "", "aaa"),
+			new:  template("api:v2", "2021-06-01T12:00:00Z", "bbb"),
+		},
+		{
+			name: "new image",
+			old:  template("api:v1", "", "aaa"),
+			new:  template("api:v2", "", "bbb"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if output := onlyRestarted(tt.old, tt.new); output != tt.expectedOutput {
+				t.Errorf("onlyRestarted() = %v, expectedOutput %v", output, tt.expectedOutput)
+			}
+		})
+	}
+}
+
+func TestShouldAlert(t *testing.T) {
+	isController := true
+	deployment := func(name string) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test", UID: types.UID(name), Annotations: map[string]string{revision: "2"}},
+			Spec:       appsv1.DeploymentSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": name}}},
+		}
+	}
+	replicaSet := func(deployment, revisionNumber, image, restartedAt string) *appsv1.ReplicaSet {
+		return &appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            fmt.Sprintf("%s-%s", deployment, revisionNumber),
+				Namespace:       "test",
+				Labels:          map[string]string{"app": deployment},
+				Annotations:     map[string]string{revision: revisionNumber},
+				OwnerReferences: []metav1.OwnerReference{{UID: types.UID(deployment), Controller: &isController}},
+			},
+			Spec: appsv1.ReplicaSetSpec{Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{restartedAtAnnotation: restartedAt}},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: image}}},
+			}},
+		}
+	}
+	client := k8sfake.NewSimpleClientset(
+		replicaSet("restarted", "1", "app:1", ""), replicaSet("restarted", "2", "app:1", "2021-06-01T12:00:00Z"),
+		replicaSet("changed", "1", "app:1", ""), replicaSet("changed", "2", "app:2", ""),
+	)
+
+	tests := []struct {
+		level          string
+		deployment     string
+		expectedOutput map[string]bool
+	}{
+		{level: hermodAlertAll, expectedOutput: map[string]bool{"started": true, "succeeded": true, "failed": true}},
+		{level: hermodAlertResult, expectedOutput: map[string]bool{"started": false, "succeeded": true, "failed": true}},
+		{level: hermodAlertFailure, expectedOutput: map[string]bool{"started": false, "succeeded": false, "failed": true}},
+		{level: hermodAlertNone, expectedOutput: map[string]bool{"started": false, "succeeded": false, "failed": false}},
+		{level: hermodAlertChangesOnly, deployment: "changed", expectedOutput: map[string]bool{"started": true, "succeeded": true, "failed": true}},
+		{level: hermodAlertChangesOnly, deployment: "restarted", expectedOutput: map[string]bool{"started": false, "succeeded": false, "failed": true}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s %s", tt.level, tt.deployment), func(t *testing.T) {
+			b := &deploymentInformer{Context: context.Background(), client: client}
+			for outcome, expected := range tt.expectedOutput {
+				if output := b.shouldAlert(tt.level, outcome, deployment(tt.deployment)); output != expected {
+					t.Errorf("shouldAlert(%v) = %v, expectedOutput %v", outcome, output, expected)
+				}
+			}
+		})
+	}
+}
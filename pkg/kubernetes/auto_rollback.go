@@ -0,0 +1,102 @@
+package kubernetes
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/uswitch/hermod/pkg/templates"
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+const (
+	hermodAutoRollbackAnnotation           = "hermod.uswitch.com/auto-rollback"
+	hermodLastSuccessfulRevisionAnnotation = "hermod.uswitch.com/last-successful-revision"
+	hermodAutoRollbackHistoryAnnotation    = "hermod.uswitch.com/auto-rollback-history"
+	hermodAutoRolledBackToAnnotation       = "hermod.uswitch.com/auto-rolled-back-to"
+)
+
+// AutoRollbackOptions limits how often Hermod automatically rolls back a deployment
+type AutoRollbackOptions struct {
+	// Limit is the maximum number of automatic rollbacks of a deployment within Window
+	Limit  int
+	Window time.Duration
+}
+
+// autoRollback will roll a failed deployment back to the last revision recorded as `pass` when it opted in,
+// returning a message describing what was done and why, or an empty message when nothing was attempted,
+// and the same details for message templates
+func (b *deploymentInformer) autoRollback(deployment *appsv1.Deployment, reason string) (string, templates.AutoRollback, error) {
+	details := templates.AutoRollback{Reason: reason}
+
+	enabled, err := b.getSetting(deployment, hermodAutoRollbackAnnotation)
+	if err != nil {
+		return "", details, err
+	}
+	if enabled != "true" {
+		return "", details, nil
+	}
+
+	failedRevision := deployment.Annotations[revision]
+	notRolledBack := func(why string, err error) (string, templates.AutoRollback, error) {
+		details.NotRolledBack = why
+		return fmt.Sprintf("*Not rolling back Deployment `%s` in `%s` namespace on `%s` cluster automatically:* %s", deployment.Name, deployment.Namespace, getClusterName(), why), details, err
+	}
+
+	if deployment.Spec.Paused {
+		return notRolledBack("the deployment is paused.", nil)
+	}
+
+	target := deployment.Annotations[hermodLastSuccessfulRevisionAnnotation]
+	if target == "" {
+		return notRolledBack("no revision has been recorded as successful yet.", nil)
+	}
+	if target == failedRevision {
+		return notRolledBack(fmt.Sprintf("the failed revision %s is the last successful revision.", failedRevision), nil)
+	}
+
+	// never roll back a rollout which was itself an automatic rollback, that only leads to loops
+	if rolledBack, ok := detectRollback(b.Context, b.client, deployment); ok && rolledBack.revision == deployment.Annotations[hermodAutoRolledBackToAnnotation] {
+		return notRolledBack(fmt.Sprintf("revision %s is already an automatic rollback to revision %s.", failedRevision, rolledBack.revision), nil)
+	}
+
+	now := time.Now()
+	history := recentRollbacks(deployment.Annotations[hermodAutoRollbackHistoryAnnotation], now.Add(-b.AutoRollback.Window))
+	if len(history) >= b.AutoRollback.Limit {
+		return notRolledBack(fmt.Sprintf("it was already rolled back automatically %d time(s) in the last %s.", len(history), b.AutoRollback.Window), nil)
+	}
+
+	err = rollbackDeployment(b.Context, b.client, deployment, target)
+	if err != nil {
+		return notRolledBack(err.Error(), err)
+	}
+	autoRollbackDeploymentTotal.Inc()
+	details.RolledBack, details.Revision = true, target
+
+	history = append(history, now.UTC().Format(time.RFC3339))
+	err = addAnnotations(b.Context, b.client, deployment.Namespace, deployment, map[string]string{
+		hermodAutoRollbackHistoryAnnotation: strings.Join(history, ","),
+		hermodAutoRolledBackToAnnotation:    target,
+	})
+	if err != nil {
+		err = fmt.Errorf("failed to record automatic rollback: %v", err)
+	}
+
+	return fmt.Sprintf("*Automatically rolled back Deployment `%s` in `%s` namespace on `%s` cluster to revision %s because the rollout of revision %s failed (`%s`).*", deployment.Name, deployment.Namespace, getClusterName(), target, failedRevision, reason), details, err
+}
+
+// recentRollbacks will return the rollback timestamps from the comma separated history which are after since
+func recentRollbacks(history string, since time.Time) []string {
+	var recent []string
+	for _, timestamp := range strings.Split(history, ",") {
+		t, err := time.Parse(time.RFC3339, strings.TrimSpace(timestamp))
+		if err != nil {
+			continue
+		}
+		if t.After(since) {
+			recent = append(recent, t.UTC().Format(time.RFC3339))
+		}
+	}
+
+	return recent
+}
@@ -0,0 +1,87 @@
+package kubernetes
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/uswitch/hermod/pkg/github"
+	"github.com/uswitch/hermod/pkg/scm"
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+const (
+	hermodLastSuccessfulSHAAnnotation = "hermod.uswitch.com/last-successful-sha"
+
+	// maximum number of commits listed in a notification
+	maxListedCommits = 10
+)
+
+// describeRollout summarises what changed in a rollout, one notification section each
+func (b *deploymentInformer) describeRollout(deployment *appsv1.Deployment) []string {
+	var details []string
+	if changes := describeTemplateChanges(b.Context, b.client, deployment); changes != "" {
+		details = append(details, changes)
+	}
+	if changes := b.describeChanges(deployment); changes != "" {
+		details = append(details, changes)
+	}
+
+	return details
+}
+
+// describeChanges links to the changes between the last successful rollout and the sha being rolled out,
+// listing the commits in between when commit details are enabled
+func (b *deploymentInformer) describeChanges(deployment *appsv1.Deployment) string {
+	repoURL, sha := b.gitRevision(deployment)
+	previousSHA := deployment.GetAnnotations()[hermodLastSuccessfulSHAAnnotation]
+	if repoURL == "" || sha == "" || previousSHA == "" || previousSHA == sha {
+		return ""
+	}
+
+	providerName, err := b.SCMResolver.Name(repoURL, deployment.GetAnnotations()[hermodSCMAnnotation])
+	if err != nil {
+		log.Warnf("failed to select scm provider for deployment `%s` in `%s` namespace: %v", deployment.Name, deployment.Namespace, err)
+		return ""
+	}
+	provider, _ := b.SCMResolver.Provider(repoURL, providerName)
+
+	changes := []string{fmt.Sprintf("*Changes:* %s", provider.CompareURL(repoURL, previousSHA, sha))}
+
+	if b.githubOptions().CommitDetails && b.GithubClient != nil && scm.IsGitHub(providerName) {
+		repo, err := github.ParseRepository(repoURL)
+		if err != nil {
+			log.Warnf("cannot list commits for deployment `%s` in `%s` namespace: %v", deployment.Name, deployment.Namespace, err)
+			return changes[0]
+		}
+
+		commits, err := b.GithubClient.CompareCommits(b.Context, repo, previousSHA, sha)
+		if err != nil {
+			log.Warnf("failed to list commits for deployment `%s` in `%s` namespace: %v", deployment.Name, deployment.Namespace, err)
+			return changes[0]
+		}
+
+		changes = append(changes, formatCommits(commits)...)
+	}
+
+	return strings.Join(changes, "\n")
+}
+
+// formatCommits lists the most recent commits, one per line
+func formatCommits(commits []github.Commit) []string {
+	var lines []string
+	if len(commits) > maxListedCommits {
+		lines = append(lines, fmt.Sprintf("_...and %d earlier commits_", len(commits)-maxListedCommits))
+		commits = commits[len(commits)-maxListedCommits:]
+	}
+
+	for _, commit := range commits {
+		sha := commit.SHA
+		if len(sha) > 7 {
+			sha = sha[:7]
+		}
+		lines = append(lines, fmt.Sprintf("• `%s` %s - %s", sha, commit.Message, commit.Author))
+	}
+
+	return lines
+}
@@ -0,0 +1,244 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	log "github.com/sirupsen/logrus"
+	"sigs.k8s.io/yaml"
+)
+
+// how often the config file is checked for changes, mounted ConfigMaps are updated by swapping a symlink
+const configFilePollInterval = 10 * time.Second
+
+var configReloadTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "hermod_config_reloads_total",
+	Help: "The total number of config file reloads by result, success or failure",
+}, []string{"result"})
+
+// ConfigFile is the YAML config file, an alternative to the HermodConfig and NotificationPolicy custom resources
+type ConfigFile struct {
+	// Flags are command line flags by name, e.g. `github-deployments: true`, flags given on the command line take precedence
+	Flags map[string]interface{} `json:"flags,omitempty"`
+
+	// the defaults of every deployment, as in a HermodConfig
+	hermodConfigSpec
+
+	// Policies are the NotificationPolicies of namespaces
+	Policies []ConfigFilePolicy `json:"policies,omitempty"`
+}
+
+// ConfigFilePolicy is a NotificationPolicy of the config file
+type ConfigFilePolicy struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+
+	notificationPolicySpec
+}
+
+// LoadConfigFile will read and validate the config file
+func LoadConfigFile(path string) (*ConfigFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %v", err)
+	}
+
+	return parseConfigFile(data)
+}
+
+func parseConfigFile(data []byte) (*ConfigFile, error) {
+	var file ConfigFile
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid config file: %v", err)
+	}
+
+	if _, _, err := file.settings(); err != nil {
+		return nil, fmt.Errorf("invalid config file: %v", err)
+	}
+	if _, err := file.FlagArgs(); err != nil {
+		return nil, fmt.Errorf("invalid config file: %v", err)
+	}
+
+	return &file, nil
+}
+
+// HasSettings tells whether the file sets anything besides flags
+func (f *ConfigFile) HasSettings() bool {
+	return !reflect.DeepEqual(f.hermodConfigSpec, hermodConfigSpec{}) || len(f.Policies) > 0
+}
+
+// DefaultSlackChannel is the default channel set by the file, it replaces --default-slack-channel
+func (f *ConfigFile) DefaultSlackChannel() string {
+	return f.Slack.DefaultChannel
+}
+
+// FlagArgs will return the flags of the file as command line arguments, to be parsed before those of the command line
+func (f *ConfigFile) FlagArgs() ([]string, error) {
+	var names []string
+	for name := range f.Flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var args []string
+	for _, name := range names {
+		switch value := f.Flags[name].(type) {
+		case bool:
+			if value {
+				args = append(args, "--"+name)
+			} else {
+				args = append(args, "--no-"+name)
+			}
+		case string, float64:
+			args = append(args, fmt.Sprintf("--%s=%v", name, value))
+		case []interface{}:
+			for _, item := range value {
+				args = append(args, fmt.Sprintf("--%s=%v", name, item))
+			}
+		case map[string]interface{}:
+			var keys []string
+			for key := range value {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				args = append(args, fmt.Sprintf("--%s=%s=%v", name, key, value[key]))
+			}
+		default:
+			return nil, fmt.Errorf("unsupported value of flag %s: %v", name, value)
+		}
+	}
+
+	return args, nil
+}
+
+// settings will validate the defaults and policies of the file
+func (f *ConfigFile) settings() (*clusterConfig, map[string][]notificationPolicy, error) {
+	var cluster *clusterConfig
+	if !reflect.DeepEqual(f.hermodConfigSpec, hermodConfigSpec{}) {
+		var err error
+		cluster, err = parseHermodConfig(f.hermodConfigSpec)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	namespaces := map[string][]notificationPolicy{}
+	seen := map[string]bool{}
+	for _, p := range f.Policies {
+		if p.Namespace == "" || p.Name == "" {
+			return nil, nil, fmt.Errorf("policies need a namespace and a name")
+		}
+		key := p.Namespace + "/" + p.Name
+		if seen[key] {
+			return nil, nil, fmt.Errorf("policy %s is defined twice", key)
+		}
+		seen[key] = true
+
+		policy, err := parseNotificationPolicy(p.Name, p.notificationPolicySpec)
+		if err != nil {
+			return nil, nil, fmt.Errorf("policy %s/%s: %v", p.Namespace, p.Name, err)
+		}
+		namespaces[p.Namespace] = append(namespaces[p.Namespace], policy)
+	}
+	for _, namespacePolicies := range namespaces {
+		sort.Slice(namespacePolicies, func(i, j int) bool { return namespacePolicies[i].name < namespacePolicies[j].name })
+	}
+
+	return cluster, namespaces, nil
+}
+
+// configFileState is the config file last read, the flags it was loaded with and the last failure to reload it
+type configFileState struct {
+	data    []byte
+	flags   map[string]interface{}
+	failure string
+}
+
+// watchConfigFile will apply the config file and reapply it whenever it changes, flags included when ReloadSettings is set
+func (b *deploymentInformer) watchConfigFile(ctx context.Context) {
+	state := &configFileState{}
+	b.reloadConfigFile(state)
+
+	go func() {
+		ticker := time.NewTicker(configFilePollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				b.reloadConfigFile(state)
+			}
+		}
+	}()
+}
+
+// reloadConfigFile will apply the config file when it changed since it was last read, keeping the previous configuration when it is invalid
+func (b *deploymentInformer) reloadConfigFile(state *configFileState) {
+	data, err := os.ReadFile(b.ConfigFile)
+	if err == nil && state.data != nil && bytes.Equal(data, state.data) {
+		state.failure = ""
+		return
+	}
+
+	var file *ConfigFile
+	var flags map[string]interface{}
+	var cluster *clusterConfig
+	var namespaces map[string][]notificationPolicy
+	settings := b.currentSettings()
+	flagsChanged := false
+	if err == nil {
+		state.data = data
+		file, err = parseConfigFile(data)
+	}
+	if err == nil {
+		flags = file.Flags
+		if flags == nil {
+			flags = map[string]interface{}{}
+		}
+		// the flags of the file loaded at startup are already applied
+		flagsChanged = state.flags != nil && !reflect.DeepEqual(state.flags, flags)
+		if flagsChanged && b.ReloadSettings != nil {
+			settings, err = b.ReloadSettings(file)
+		}
+	}
+	if err == nil {
+		cluster, namespaces, _ = file.settings()
+		err = checkGithubSink(cluster, settings.GithubClient)
+	}
+	if err != nil {
+		// failures are reported once, until the file changes or can be read again
+		message := fmt.Sprintf("failed to reload config file %s, keeping the previous configuration: %v", b.ConfigFile, err)
+		if message != state.failure {
+			state.failure = message
+			log.Error(message)
+			sentry.CaptureMessage(message)
+			configReloadTotal.WithLabelValues("failure").Inc()
+		}
+		return
+	}
+	state.failure = ""
+
+	if flagsChanged {
+		if b.ReloadSettings != nil {
+			b.replaceSettings(settings)
+		} else {
+			log.Warnf("flags of config file %s changed, restart hermod to apply them", b.ConfigFile)
+		}
+	}
+	b.policies.replace(cluster, namespaces)
+	state.flags = flags
+
+	log.Infof("loaded config file %s", b.ConfigFile)
+	configReloadTotal.WithLabelValues("success").Inc()
+}
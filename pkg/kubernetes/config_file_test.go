@@ -0,0 +1,228 @@
+package kubernetes
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseConfigFile(t *testing.T) {
+	tests := []struct {
+		name         string
+		data         string
+		expectedArgs []string
+		expectError  bool
+	}{
+		{
+			name: "flags and settings",
+			data: `
+flags:
+  level: debug
+  github-deployments: true
+  git-annotation-warning: false
+  auto-rollback-limit: 2
+  exclude-namespace: [kube-*, "*-preview"]
+  scm-host:
+    git.example.com: gitlab
+slack:
+  defaultChannel: deploys
+alertLevel: result
+templates:
+  slack-failed: "{{ .Name }} failed"
+policies:
+- namespace: payments
+  name: workers
+  selector:
+    matchLabels:
+      tier: worker
+  slack:
+    channels: [payments-deploys]
+  alertLevel: failure
+`,
+			expectedArgs: []string{"--auto-rollback-limit=2", "--exclude-namespace=kube-*", "--exclude-namespace=*-preview", "--no-git-annotation-warning", "--github-deployments", "--level=debug", "--scm-host=git.example.com=gitlab"},
+		},
+		{
+			name:        "unknown setting",
+			data:        "alert-level: result\n",
+			expectError: true,
+		},
+		{
+			name:        "invalid template",
+			data:        "templates:\n  slack-paused: paused\n",
+			expectError: true,
+		},
+		{
+			name:        "policy without namespace",
+			data:        "policies:\n- name: workers\n  alertLevel: failure\n",
+			expectError: true,
+		},
+		{
+			name:        "policy defined twice",
+			data:        "policies:\n- namespace: payments\n  name: workers\n- namespace: payments\n  name: workers\n",
+			expectError: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := parseConfigFile([]byte(tt.data))
+			if (err != nil) != tt.expectError {
+				t.Fatalf("parseConfigFile() error = %v, expectError %v", err, tt.expectError)
+			}
+			if err != nil {
+				return
+			}
+			args, _ := file.FlagArgs()
+			if !reflect.DeepEqual(args, tt.expectedArgs) {
+				t.Errorf("FlagArgs() = %v, expectedOutput %v", args, tt.expectedArgs)
+			}
+		})
+	}
+}
+
+func TestReloadConfigFile(t *testing.T) {
+	path := t.TempDir() + "/config.yaml"
+	deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: "payments", Labels: map[string]string{"tier": "worker"}}}
+	b := &deploymentInformer{ConfigFile: path}
+	state := &configFileState{}
+
+	tests := []struct {
+		name           string
+		data           string
+		expectedOutput string
+	}{
+		{
+			name:           "valid file",
+			data:           "alertLevel: result\npolicies:\n- namespace: payments\n  name: workers\n  alertLevel: failure\n",
+			expectedOutput: "failure",
+		},
+		{
+			name:           "policy removed",
+			data:           "alertLevel: result\n",
+			expectedOutput: "result",
+		},
+		{
+			name:           "invalid file keeps the previous configuration",
+			data:           "alertLevel: sometimes\n",
+			expectedOutput: "result",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(path, []byte(tt.data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			b.reloadConfigFile(state)
+			if output := b.policies.setting(deployment, hermodAlertAnnotation); output != tt.expectedOutput {
+				t.Errorf("setting() = %v, expectedOutput %v", output, tt.expectedOutput)
+			}
+		})
+	}
+}
+
+func TestReloadConfigFileFlags(t *testing.T) {
+	path := t.TempDir() + "/config.yaml"
+	var reloaded []string
+	b := &deploymentInformer{
+		ConfigFile: path,
+		Settings:   Settings{EscalationDelay: 30 * time.Minute},
+		ReloadSettings: func(file *ConfigFile) (Settings, error) {
+			args, _ := file.FlagArgs()
+			reloaded = append(reloaded, strings.Join(args, " "))
+			if file.Flags["escalation-delay"] == "never" {
+				return Settings{}, fmt.Errorf("invalid escalation delay")
+			}
+			return Settings{EscalationDelay: time.Hour}, nil
+		},
+	}
+	state := &configFileState{}
+
+	tests := []struct {
+		name             string
+		data             string
+		expectedReloaded []string
+		expectedOutput   time.Duration
+		expectedFailure  bool
+	}{
+		{
+			name:           "flags of the file at startup are already applied",
+			data:           "flags:\n  escalation-delay: 30m\n",
+			expectedOutput: 30 * time.Minute,
+		},
+		{
+			name:             "changed flags are applied",
+			data:             "flags:\n  escalation-delay: 1h\n",
+			expectedReloaded: []string{"--escalation-delay=1h"},
+			expectedOutput:   time.Hour,
+		},
+		{
+			name:             "invalid flags keep the previous settings",
+			data:             "flags:\n  escalation-delay: never\n",
+			expectedReloaded: []string{"--escalation-delay=1h", "--escalation-delay=never"},
+			expectedOutput:   time.Hour,
+			expectedFailure:  true,
+		},
+		{
+			name:             "invalid flags are reloaded again when the file changes",
+			data:             "flags:\n  escalation-delay: never\nalertLevel: result\n",
+			expectedReloaded: []string{"--escalation-delay=1h", "--escalation-delay=never", "--escalation-delay=never"},
+			expectedOutput:   time.Hour,
+			expectedFailure:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(path, []byte(tt.data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			b.reloadConfigFile(state)
+			if !reflect.DeepEqual(reloaded, tt.expectedReloaded) {
+				t.Errorf("ReloadSettings() calls = %v, expectedReloaded %v", reloaded, tt.expectedReloaded)
+			}
+			if output := b.EscalationDelay; output != tt.expectedOutput {
+				t.Errorf("EscalationDelay = %v, expectedOutput %v", output, tt.expectedOutput)
+			}
+			if failure := state.failure != ""; failure != tt.expectedFailure {
+				t.Errorf("failure = %q, expectedFailure %v", state.failure, tt.expectedFailure)
+			}
+		})
+	}
+}
+
+func TestReloadConfigFileFailures(t *testing.T) {
+	path := t.TempDir() + "/config.yaml"
+	b := &deploymentInformer{ConfigFile: path}
+	state := &configFileState{}
+	failures := func() float64 { return testutil.ToFloat64(configReloadTotal.WithLabelValues("failure")) }
+	initial := failures()
+
+	steps := []struct {
+		data             string // the file is removed when empty
+		expectedFailures float64
+	}{
+		{data: "", expectedFailures: 1},
+		{data: "", expectedFailures: 1},
+		{data: "alertLevel: sometimes\n", expectedFailures: 2},
+		{data: "alertLevel: sometimes\n", expectedFailures: 2},
+		{data: "", expectedFailures: 3},
+		{data: "alertLevel: result\n", expectedFailures: 3},
+		{data: "", expectedFailures: 4},
+	}
+	for i, step := range steps {
+		if step.data == "" {
+			os.Remove(path)
+		} else if err := os.WriteFile(path, []byte(step.data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		b.reloadConfigFile(state)
+		if output := failures() - initial; output != step.expectedFailures {
+			t.Errorf("step %d: failures = %v, expectedFailures %v", i, output, step.expectedFailures)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package kubernetes
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/uswitch/hermod/pkg/slack"
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+// deployerEmail will return the email of whoever shipped the rollout, from the deployer annotation or else the author of the deployed commit
+func (b *deploymentInformer) deployerEmail(deployment *appsv1.Deployment) string {
+	if email := deployment.GetAnnotations()[b.DeployerEmailAnnotation]; b.DeployerEmailAnnotation != "" && email != "" {
+		return email
+	}
+
+	if b.GithubClient == nil {
+		return ""
+	}
+
+	repo, sha, ok := b.githubRepository(deployment)
+	if !ok {
+		return ""
+	}
+
+	email, err := b.GithubClient.CommitAuthorEmail(b.Context, repo, sha)
+	if err != nil {
+		log.Warnf("failed to find the author of deployment `%s` in `%s` namespace: %v", deployment.Name, deployment.Namespace, err)
+		return ""
+	}
+
+	return email
+}
+
+// notifyDeployer will send the failure message to whoever shipped the rollout
+func (b *deploymentInformer) notifyDeployer(deployment *appsv1.Deployment, alert slack.Message) {
+	email := b.deployerEmail(deployment)
+	if email == "" {
+		log.Debugf("no deployer found for deployment `%s` in `%s` namespace", deployment.Name, deployment.Namespace)
+		return
+	}
+
+	// the deployer is messaged directly, there is nobody else to mention
+	alert.Mentions = nil
+
+	// deployers without a Slack account, such as noreply commit authors, are common so this is not reported to Sentry
+	err := b.SlackClient.SendDirectMessage(email, alert)
+	if err != nil {
+		log.Warnf("failed to send slack message to deployer of `%s/%s`: %v", deployment.Namespace, deployment.Name, err)
+	}
+}
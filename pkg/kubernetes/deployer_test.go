@@ -0,0 +1,32 @@
+package kubernetes
+
+import (
+	"testing"
+)
+
+func TestDeployerEmail(t *testing.T) {
+	tests := []struct {
+		name           string
+		annotations    map[string]string
+		expectedOutput string
+	}{
+		{
+			name:           "deployer annotation",
+			annotations:    map[string]string{"hermod.uswitch.com/deployer-email": "jane@example.com"},
+			expectedOutput: "jane@example.com",
+		},
+		{
+			name:        "no deployer annotation and no github client",
+			annotations: map[string]string{"hermod.uswitch.com/gitsha": "abc"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &deploymentInformer{Settings: Settings{DeployerEmailAnnotation: "hermod.uswitch.com/deployer-email"}}
+			deployment := testDeployment("", tt.annotations, nil)
+			if output := b.deployerEmail(deployment); output != tt.expectedOutput {
+				t.Errorf("deployerEmail() = %v, expectedOutput %v", output, tt.expectedOutput)
+			}
+		})
+	}
+}
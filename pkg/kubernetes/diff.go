@@ -0,0 +1,175 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// templateDiff holds the notable changes between two pod templates
+type templateDiff struct {
+	images    []string
+	env       []string
+	resources []string
+}
+
+func (d templateDiff) empty() bool {
+	return len(d.images) == 0 && len(d.env) == 0 && len(d.resources) == 0
+}
+
+// String renders the changes as a compact summary for notifications
+func (d templateDiff) String() string {
+	var lines []string
+	if len(d.images) > 0 {
+		lines = append(lines, "*Image changes:*")
+		lines = append(lines, d.images...)
+	}
+	if len(d.env) > 0 {
+		lines = append(lines, "*Environment changes:*")
+		lines = append(lines, d.env...)
+	}
+	if len(d.resources) > 0 {
+		lines = append(lines, "*Resource changes:*")
+		lines = append(lines, d.resources...)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// diffPodTemplates compares the containers of two pod templates, matching containers by name
+func diffPodTemplates(old, new corev1.PodTemplateSpec) templateDiff {
+	var diff templateDiff
+
+	oldContainers := containersByName(old.Spec)
+	for _, container := range append(append([]corev1.Container{}, new.Spec.InitContainers...), new.Spec.Containers...) {
+		oldContainer, ok := oldContainers[container.Name]
+		if !ok {
+			diff.images = append(diff.images, fmt.Sprintf("• `%s`: added `%s`", container.Name, container.Image))
+			continue
+		}
+		delete(oldContainers, container.Name)
+
+		if oldContainer.Image != container.Image {
+			diff.images = append(diff.images, fmt.Sprintf("• `%s`: `%s` → `%s`", container.Name, oldContainer.Image, container.Image))
+		}
+
+		diff.env = append(diff.env, diffEnv(container.Name, oldContainer.Env, container.Env)...)
+		diff.resources = append(diff.resources, diffResources(container.Name, "requests", oldContainer.Resources.Requests, container.Resources.Requests)...)
+		diff.resources = append(diff.resources, diffResources(container.Name, "limits", oldContainer.Resources.Limits, container.Resources.Limits)...)
+	}
+
+	var removed []string
+	for name := range oldContainers {
+		removed = append(removed, name)
+	}
+	sort.Strings(removed)
+	for _, name := range removed {
+		diff.images = append(diff.images, fmt.Sprintf("• `%s`: removed `%s`", name, oldContainers[name].Image))
+	}
+
+	return diff
+}
+
+func containersByName(spec corev1.PodSpec) map[string]corev1.Container {
+	containers := map[string]corev1.Container{}
+	for _, container := range spec.InitContainers {
+		containers[container.Name] = container
+	}
+	for _, container := range spec.Containers {
+		containers[container.Name] = container
+	}
+	return containers
+}
+
+// diffEnv lists the environment variables added to or removed from a container, values are left out as they may be sensitive
+func diffEnv(container string, old, new []corev1.EnvVar) []string {
+	oldNames := map[string]bool{}
+	for _, env := range old {
+		oldNames[env.Name] = true
+	}
+
+	var changes []string
+	for _, env := range new {
+		if !oldNames[env.Name] {
+			changes = append(changes, fmt.Sprintf("• `%s`: added `%s`", container, env.Name))
+		}
+		delete(oldNames, env.Name)
+	}
+
+	var removed []string
+	for name := range oldNames {
+		removed = append(removed, name)
+	}
+	sort.Strings(removed)
+	for _, name := range removed {
+		changes = append(changes, fmt.Sprintf("• `%s`: removed `%s`", container, name))
+	}
+
+	return changes
+}
+
+func diffResources(container, kind string, old, new corev1.ResourceList) []string {
+	names := map[corev1.ResourceName]bool{}
+	for name := range old {
+		names[name] = true
+	}
+	for name := range new {
+		names[name] = true
+	}
+
+	var sorted []string
+	for name := range names {
+		sorted = append(sorted, string(name))
+	}
+	sort.Strings(sorted)
+
+	var changes []string
+	for _, name := range sorted {
+		oldQuantity, oldOk := old[corev1.ResourceName(name)]
+		newQuantity, newOk := new[corev1.ResourceName(name)]
+
+		switch {
+		case !oldOk:
+			changes = append(changes, fmt.Sprintf("• `%s`: %s %s set to `%s`", container, name, kind, newQuantity.String()))
+		case !newOk:
+			changes = append(changes, fmt.Sprintf("• `%s`: %s %s `%s` removed", container, name, kind, oldQuantity.String()))
+		case oldQuantity.Cmp(newQuantity) != 0:
+			changes = append(changes, fmt.Sprintf("• `%s`: %s %s `%s` → `%s`", container, name, kind, oldQuantity.String(), newQuantity.String()))
+		}
+	}
+
+	return changes
+}
+
+// describeTemplateChanges summarises the changes between the pod templates of the previous and current ReplicaSets of a deployment
+func describeTemplateChanges(ctx context.Context, client kubernetes.Interface, deployment *appsv1.Deployment) string {
+	replicaSets, err := getDeploymentReplicaSets(ctx, client, deployment)
+	if err != nil {
+		log.Warnf("failed to get the replicasets of deployment `%s` in `%s` namespace: %v", deployment.Name, deployment.Namespace, err)
+		return ""
+	}
+
+	currentRS, previousRS := currentAndPreviousReplicaSets(replicaSets, deployment.Annotations[revision])
+	if previousRS == nil {
+		return ""
+	}
+
+	// the deployment spec is the current template when its replicaset hasn't been created yet
+	currentTemplate := deployment.Spec.Template
+	if currentRS != nil {
+		currentTemplate = currentRS.Spec.Template
+	}
+
+	diff := diffPodTemplates(previousRS.Spec.Template, currentTemplate)
+	if diff.empty() {
+		return ""
+	}
+
+	return diff.String()
+}
@@ -0,0 +1,59 @@
+package kubernetes
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestDiffPodTemplates(t *testing.T) {
+	template := func(containers ...corev1.Container) corev1.PodTemplateSpec {
+		return corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: containers}}
+	}
+	tests := []struct {
+		name           string
+		old            corev1.PodTemplateSpec
+		new            corev1.PodTemplateSpec
+		expectedOutput string
+	}{
+		{
+			name:           "no changes",
+			old:            template(corev1.Container{Name: "app", Image: "app:1"}),
+			new:            template(corev1.Container{Name: "app", Image: "app:1"}),
+			expectedOutput: "",
+		},
+		{
+			name:           "image changes",
+			old:            template(corev1.Container{Name: "app", Image: "app:1"}, corev1.Container{Name: "proxy", Image: "proxy:1"}),
+			new:            template(corev1.Container{Name: "app", Image: "app:2"}, corev1.Container{Name: "sidecar", Image: "sidecar:1"}),
+			expectedOutput: "*Image changes:*\n• `app`: `app:1` → `app:2`\n• `sidecar`: added `sidecar:1`\n• `proxy`: removed `proxy:1`",
+		},
+		{
+			name: "env and resource changes",
+			old: template(corev1.Container{
+				Name:      "app",
+				Image:     "app:1",
+				Env:       []corev1.EnvVar{{Name: "A", Value: "a"}, {Name: "B", Value: "b"}},
+				Resources: corev1.ResourceRequirements{Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("100Mi")}},
+			}),
+			new: template(corev1.Container{
+				Name:  "app",
+				Image: "app:1",
+				Env:   []corev1.EnvVar{{Name: "A", Value: "changed"}, {Name: "C", Value: "c"}},
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("10m")},
+					Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("200Mi")},
+				},
+			}),
+			expectedOutput: "*Environment changes:*\n• `app`: added `C`\n• `app`: removed `B`\n*Resource changes:*\n• `app`: cpu requests set to `10m`\n• `app`: memory limits `100Mi` → `200Mi`",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if output := diffPodTemplates(tt.old, tt.new).String(); output != tt.expectedOutput {
+				t.Errorf("diffPodTemplates() = %v, expectedOutput %v", output, tt.expectedOutput)
+			}
+		})
+	}
+}
@@ -0,0 +1,269 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	log "github.com/sirupsen/logrus"
+	"github.com/uswitch/hermod/pkg/slack"
+	"github.com/uswitch/hermod/pkg/templates"
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+const (
+	// how often digest messages are updated
+	digestUpdateInterval = 15 * time.Second
+	// maximum number of failed deployments listed in a digest
+	maxDigestFailures = 20
+)
+
+// DigestOptions configures collapsing bursts of rollouts in a channel into one digest message
+type DigestOptions struct {
+	// Threshold is the number of rollout messages to a channel within Window which starts a digest, 0 disables digests
+	Threshold int
+	// Window is the period bursts are detected over, a digest ends once no rollout was reported for this long
+	Window time.Duration
+}
+
+type digestEvent struct {
+	at         time.Time
+	deployment string
+	outcome    string
+}
+
+// channelDigest tracks the recent rollout messages of a channel and, during a burst, the digest collapsing them
+type channelDigest struct {
+	recent []digestEvent
+
+	active      bool
+	ref         slack.MessageRef
+	posted      bool
+	started     time.Time
+	lastEvent   time.Time
+	deployments []string
+	outcomes    map[string]string
+	dirty       bool
+}
+
+type digests struct {
+	sync.Mutex
+	channels map[string]*channelDigest
+}
+
+type digestUpdate struct {
+	channel   string
+	ref       slack.MessageRef
+	posted    bool
+	message   slack.Message
+	final     bool
+	lastEvent time.Time
+}
+
+// add will record a rollout message to the channel and tell whether it is collapsed into a digest
+func (d *digests) add(options DigestOptions, channel, deployment, outcome string, now time.Time) bool {
+	if options.Threshold <= 0 {
+		return false
+	}
+
+	d.Lock()
+	defer d.Unlock()
+
+	if d.channels == nil {
+		d.channels = map[string]*channelDigest{}
+	}
+	c, ok := d.channels[channel]
+	if !ok {
+		c = &channelDigest{}
+		d.channels[channel] = c
+	}
+
+	if c.active {
+		c.record(deployment, outcome)
+		c.lastEvent = now
+		return true
+	}
+
+	// forget the messages sent before the window
+	var recent []digestEvent
+	for _, event := range c.recent {
+		if now.Sub(event.at) < options.Window {
+			recent = append(recent, event)
+		}
+	}
+	c.recent = append(recent, digestEvent{at: now, deployment: deployment, outcome: outcome})
+
+	if len(c.recent) < options.Threshold {
+		return false
+	}
+
+	// a burst, the digest starts with the rollouts already reported in the window
+	c.active, c.started, c.lastEvent, c.outcomes = true, now, now, map[string]string{}
+	for _, event := range c.recent {
+		c.record(event.deployment, event.outcome)
+	}
+	c.recent = nil
+
+	return true
+}
+
+func (c *channelDigest) record(deployment, outcome string) {
+	if _, ok := c.outcomes[deployment]; !ok {
+		c.deployments = append(c.deployments, deployment)
+	}
+	c.outcomes[deployment] = outcome
+	c.dirty = true
+}
+
+// due will return the digests to post or update, the final update of those without rollouts for a window
+// is returned until it is sent and the digest ended
+func (d *digests) due(options DigestOptions, now time.Time) []digestUpdate {
+	d.Lock()
+	defer d.Unlock()
+
+	var updates []digestUpdate
+	for channel, c := range d.channels {
+		if !c.active {
+			continue
+		}
+
+		final := now.Sub(c.lastEvent) >= options.Window
+		if c.dirty || final {
+			updates = append(updates, digestUpdate{channel: channel, ref: c.ref, posted: c.posted, message: c.message(final), final: final, lastEvent: c.lastEvent})
+			c.dirty = false
+		}
+	}
+
+	return updates
+}
+
+// setPosted will record where the digest of the channel was posted
+func (d *digests) setPosted(channel string, ref slack.MessageRef) {
+	d.Lock()
+	defer d.Unlock()
+
+	if c, ok := d.channels[channel]; ok {
+		c.ref, c.posted = ref, true
+	}
+}
+
+// end will forget the digest of the channel once its final message was sent, unless rollouts were added to it since
+func (d *digests) end(channel string, lastEvent time.Time) {
+	d.Lock()
+	defer d.Unlock()
+
+	if c, ok := d.channels[channel]; ok && c.lastEvent.Equal(lastEvent) {
+		delete(d.channels, channel)
+	}
+}
+
+// retry will send the digest of the channel again on the next update, after sending it failed
+func (d *digests) retry(channel string) {
+	d.Lock()
+	defer d.Unlock()
+
+	if c, ok := d.channels[channel]; ok {
+		c.dirty = true
+	}
+}
+
+// message summarises the rollouts of the digest, breaking out the failures
+func (c *channelDigest) message(final bool) slack.Message {
+	var inProgress, succeeded int
+	var failed []string
+	for _, deployment := range c.deployments {
+		switch c.outcomes[deployment] {
+		case templates.PhaseStarted:
+			inProgress++
+		case templates.PhaseSucceeded:
+			succeeded++
+		case templates.PhaseFailed:
+			failed = append(failed, deployment)
+		}
+	}
+
+	message := slack.Message{
+		Header:  "Rollout digest",
+		Summary: fmt.Sprintf("*%d rollouts in progress, %d succeeded, %d failed on `%s` cluster since %s.*", inProgress, succeeded, len(failed), getClusterName(), c.started.UTC().Format(time.RFC3339)),
+		Color:   slack.OrangeColor,
+	}
+	if final {
+		message.Context = []string{"Burst over, messages are sent for each rollout again"}
+	}
+
+	switch {
+	case len(failed) > 0:
+		message.Color = slack.RedColor
+
+		var lines []string
+		for i, deployment := range failed {
+			if i == maxDigestFailures {
+				lines = append(lines, fmt.Sprintf("_...and %d more_", len(failed)-maxDigestFailures))
+				break
+			}
+			lines = append(lines, fmt.Sprintf("• `%s`", deployment))
+		}
+		message.Sections = []string{"*Failed:*\n" + strings.Join(lines, "\n")}
+	case inProgress == 0:
+		message.Color = slack.GreenColor
+	}
+
+	return message
+}
+
+// updateDigests will post and update the digest messages of channels with a burst of rollouts
+func (b *deploymentInformer) updateDigests(ctx context.Context) {
+	ticker := time.NewTicker(digestUpdateInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for _, update := range b.digests.due(b.currentSettings().Digest, now) {
+				var err error
+				if update.posted {
+					err = b.SlackClient.UpdateMessage(update.ref, update.message)
+				} else {
+					var ref slack.MessageRef
+					ref, err = b.SlackClient.PostMessage(update.channel, update.message)
+					if err == nil {
+						b.digests.setPosted(update.channel, ref)
+					}
+				}
+				if err != nil {
+					message := fmt.Sprintf("failed to send slack digest: %v", err)
+					log.Error(message)
+					sentry.CaptureMessage(message)
+					b.digests.retry(update.channel)
+					continue
+				}
+				if update.final {
+					b.digests.end(update.channel, update.lastEvent)
+				}
+			}
+		}
+	}
+}
+
+// sendOrCollapse will send the message to every channel the outcome is routed to, unless the rollout is grouped with
+// its release or the channel is in a burst of rollouts in which case the outcome is added to its digest, failures are
+// still sent on their own
+func (b *deploymentInformer) sendOrCollapse(routes slackRoutes, outcome string, deployment *appsv1.Deployment, message slack.Message) {
+	if b.groupRelease(routes, outcome, deployment) {
+		return
+	}
+
+	now := time.Now()
+	for _, channel := range routes[outcome] {
+		collapsed := b.digests.add(b.Digest, channel, fmt.Sprintf("%s/%s", deployment.Namespace, deployment.Name), outcome, now)
+		if collapsed && outcome != templates.PhaseFailed {
+			continue
+		}
+		b.sendToChannel(channel, message)
+	}
+}
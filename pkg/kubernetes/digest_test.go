@@ -0,0 +1,122 @@
+package kubernetes
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/uswitch/hermod/pkg/slack"
+)
+
+func TestDigests(t *testing.T) {
+	options := DigestOptions{Threshold: 3, Window: 2 * time.Minute}
+	start := time.Date(2021, 6, 5, 6, 0, 0, 0, time.UTC)
+	d := digests{}
+
+	tests := []struct {
+		name           string
+		deployment     string
+		outcome        string
+		at             time.Time
+		expectedOutput bool
+	}{
+		{name: "first rollout", deployment: "payments/api", outcome: "started", at: start},
+		{name: "rollout outside the window", deployment: "payments/worker", outcome: "started", at: start.Add(3 * time.Minute)},
+		{name: "second rollout in the window", deployment: "payments/web", outcome: "started", at: start.Add(4 * time.Minute)},
+		{name: "burst", deployment: "payments/cron", outcome: "started", at: start.Add(4 * time.Minute), expectedOutput: true},
+		{name: "during the burst", deployment: "payments/web", outcome: "failed", at: start.Add(5 * time.Minute), expectedOutput: true},
+		{name: "other channel", deployment: "search/api", outcome: "started", at: start.Add(5 * time.Minute)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			channel := "deploys"
+			if tt.deployment == "search/api" {
+				channel = "search"
+			}
+			if output := d.add(options, channel, tt.deployment, tt.outcome, tt.at); output != tt.expectedOutput {
+				t.Errorf("add() = %v, expectedOutput %v", output, tt.expectedOutput)
+			}
+		})
+	}
+
+	updates := d.due(options, start.Add(5*time.Minute))
+	if len(updates) != 1 || updates[0].channel != "deploys" {
+		t.Fatalf("due() = %v, expected an update of the deploys digest", updates)
+	}
+	expectedSummary := "*2 rollouts in progress, 0 succeeded, 1 failed"
+	if !strings.HasPrefix(updates[0].message.Summary, expectedSummary) || updates[0].message.Sections[0] != "*Failed:*\n• `payments/web`" {
+		t.Errorf("due() message = %v, %v, expectedOutput %v", updates[0].message.Summary, updates[0].message.Sections, expectedSummary)
+	}
+
+	if updates := d.due(options, start.Add(6*time.Minute)); len(updates) != 0 {
+		t.Errorf("due() = %v, expected no update without new rollouts", updates)
+	}
+	updates = d.due(options, start.Add(7*time.Minute))
+	if len(updates) != 1 || !updates[0].final || len(updates[0].message.Context) != 1 {
+		t.Fatalf("due() = %v, expected the final update of the digest", updates)
+	}
+	d.end(updates[0].channel, updates[0].lastEvent)
+	if output := d.add(options, "deploys", "payments/api", "started", start.Add(8*time.Minute)); output {
+		t.Errorf("add() = %v, expected messages to be sent after the burst", output)
+	}
+}
+
+func TestDigestsRetry(t *testing.T) {
+	options := DigestOptions{Threshold: 1, Window: 2 * time.Minute}
+	start := time.Date(2021, 6, 5, 6, 0, 0, 0, time.UTC)
+	d := digests{}
+	d.add(options, "deploys", "payments/api", "started", start)
+
+	// posting the digest failed
+	if updates := d.due(options, start.Add(15*time.Second)); len(updates) != 1 || updates[0].posted {
+		t.Fatalf("due() = %v, expected the digest to be posted", updates)
+	}
+	d.retry("deploys")
+
+	if updates := d.due(options, start.Add(30*time.Second)); len(updates) != 1 || updates[0].posted {
+		t.Fatalf("due() = %v, expected the digest to be posted again", updates)
+	}
+	d.setPosted("deploys", slack.MessageRef{Channel: "C012AB3CD", Timestamp: "1622872830.000100"})
+	d.add(options, "deploys", "payments/api", "succeeded", start.Add(40*time.Second))
+
+	if updates := d.due(options, start.Add(45*time.Second)); len(updates) != 1 || !updates[0].posted || updates[0].ref.Timestamp != "1622872830.000100" {
+		t.Errorf("due() = %v, expected the posted digest to be updated", updates)
+	}
+}
+
+func TestDigestsFinalRetry(t *testing.T) {
+	options := DigestOptions{Threshold: 1, Window: 2 * time.Minute}
+	start := time.Date(2021, 6, 5, 6, 0, 0, 0, time.UTC)
+	d := digests{}
+	d.add(options, "deploys", "payments/api", "started", start)
+	d.due(options, start.Add(15*time.Second))
+	d.setPosted("deploys", slack.MessageRef{Channel: "C012AB3CD", Timestamp: "1622872830.000100"})
+
+	// updating the digest with its final message failed
+	updates := d.due(options, start.Add(3*time.Minute))
+	if len(updates) != 1 || !updates[0].final {
+		t.Fatalf("due() = %v, expected the final update of the digest", updates)
+	}
+	d.retry("deploys")
+
+	updates = d.due(options, start.Add(3*time.Minute+15*time.Second))
+	if len(updates) != 1 || !updates[0].final || !updates[0].posted || updates[0].ref.Timestamp != "1622872830.000100" {
+		t.Fatalf("due() = %v, expected the final update of the posted digest again", updates)
+	}
+
+	// a rollout added while the final message was sent extends the digest
+	d.add(options, "deploys", "payments/web", "started", start.Add(3*time.Minute+20*time.Second))
+	d.end("deploys", updates[0].lastEvent)
+	if updates := d.due(options, start.Add(3*time.Minute+30*time.Second)); len(updates) != 1 || updates[0].final {
+		t.Fatalf("due() = %v, expected the extended digest to be updated", updates)
+	}
+
+	updates = d.due(options, start.Add(6*time.Minute))
+	if len(updates) != 1 || !updates[0].final {
+		t.Fatalf("due() = %v, expected the final update of the digest", updates)
+	}
+	d.end("deploys", updates[0].lastEvent)
+	if updates := d.due(options, start.Add(7*time.Minute)); len(updates) != 0 {
+		t.Errorf("due() = %v, expected the digest to have ended", updates)
+	}
+}
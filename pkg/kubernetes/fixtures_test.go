@@ -0,0 +1,69 @@
+package kubernetes
+
+import (
+	"context"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+// testDeployment returns the api deployment of the namespace
+func testDeployment(namespace string, annotations, labels map[string]string) *appsv1.Deployment {
+	return &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: namespace, Annotations: annotations, Labels: labels}}
+}
+
+// newNamespaceIndexer returns a namespace cache holding the namespaces, as the informer watches them
+func newNamespaceIndexer(namespaces ...*corev1.Namespace) cache.Indexer {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, namespace := range namespaces {
+		indexer.Add(namespace)
+	}
+	return indexer
+}
+
+// testSettings are the settings of the flags defaults, reading the git revision of deployments from their annotations
+func testSettings() Settings {
+	return Settings{
+		HermodGithubRepoAnnotation:      "hermod.uswitch.com/gitrepo",
+		HermodGithubCommitSHAAnnotation: "hermod.uswitch.com/gitsha",
+	}
+}
+
+// newGitOpsInformer returns an informer reading the revisions of deployments without git annotations from the
+// payments-api Argo CD Application and the apps Flux Kustomization
+func newGitOpsInformer() *deploymentInformer {
+	application := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "argoproj.io/v1alpha1",
+		"kind":       "Application",
+		"metadata":   map[string]interface{}{"name": "payments-api", "namespace": "argocd"},
+		"spec":       map[string]interface{}{"source": map[string]interface{}{"repoURL": "git@github.com:uswitch/api.git"}},
+		"status":     map[string]interface{}{"sync": map[string]interface{}{"revision": "2dafeb708437f6e537d19556d461e30aa96d4244"}},
+	}}
+	kustomization := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "kustomize.toolkit.fluxcd.io/v1",
+		"kind":       "Kustomization",
+		"metadata":   map[string]interface{}{"name": "apps", "namespace": "flux-system"},
+		"spec":       map[string]interface{}{"sourceRef": map[string]interface{}{"kind": "GitRepository", "name": "apps"}},
+		"status":     map[string]interface{}{"lastAppliedRevision": "main@sha1:5f4c3b2a1908d7e6f5a4b3c2d1e0f9a8b7c6d5e4"},
+	}}
+	repository := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "source.toolkit.fluxcd.io/v1",
+		"kind":       "GitRepository",
+		"metadata":   map[string]interface{}{"name": "apps", "namespace": "flux-system"},
+		"spec":       map[string]interface{}{"url": "https://github.com/uswitch/apps"},
+	}}
+
+	settings := testSettings()
+	settings.GitOps = GitOpsOptions{ArgoCDNamespace: "argocd", Revisions: true}
+
+	return &deploymentInformer{
+		Context:       context.Background(),
+		DynamicClient: dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), application, kustomization, repository),
+		Settings:      settings,
+	}
+}
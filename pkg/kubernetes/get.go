@@ -3,6 +3,7 @@ package kubernetes
 import (
 	"context"
 	"fmt"
+	"strconv"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -26,6 +27,54 @@ func getReplicaSet(ctx context.Context, client kubernetes.Interface, namespace s
 	return appsv1.ReplicaSet{}, nil
 }
 
+// getDeploymentReplicaSets will return the replicasets controlled by the given deployment
+func getDeploymentReplicaSets(ctx context.Context, client kubernetes.Interface, deployment *appsv1.Deployment) ([]appsv1.ReplicaSet, error) {
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector of deployment: %v", err)
+	}
+
+	rs, err := client.AppsV1().ReplicaSets(deployment.Namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the replicasets: %v", err)
+	}
+
+	var replicaSets []appsv1.ReplicaSet
+	for _, rs := range rs.Items {
+		if metav1.IsControlledBy(&rs, deployment) {
+			replicaSets = append(replicaSets, rs)
+		}
+	}
+
+	return replicaSets, nil
+}
+
+// currentAndPreviousReplicaSets will return the replicaset with the given revision number and the one with the highest revision before it
+func currentAndPreviousReplicaSets(replicaSets []appsv1.ReplicaSet, revisionNumber string) (*appsv1.ReplicaSet, *appsv1.ReplicaSet) {
+	current, err := strconv.ParseInt(revisionNumber, 10, 64)
+	if err != nil {
+		return nil, nil
+	}
+
+	var currentRS, previousRS *appsv1.ReplicaSet
+	var previous int64
+	for i, rs := range replicaSets {
+		rsRevision, err := strconv.ParseInt(rs.Annotations[revision], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		if rsRevision == current {
+			currentRS = &replicaSets[i]
+		} else if rsRevision < current && rsRevision > previous {
+			previousRS = &replicaSets[i]
+			previous = rsRevision
+		}
+	}
+
+	return currentRS, previousRS
+}
+
 // getPods will return list of pods based on given label selectors
 func getPods(ctx context.Context, client kubernetes.Interface, namespace string, labelSelector string) ([]corev1.Pod, error) {
 	pods, err := client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: labelSelector})
@@ -0,0 +1,55 @@
+package kubernetes
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCurrentAndPreviousReplicaSets(t *testing.T) {
+	replicaSet := func(name, revisionNumber string) appsv1.ReplicaSet {
+		return appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: map[string]string{revision: revisionNumber}}}
+	}
+	replicaSets := []appsv1.ReplicaSet{replicaSet("a", "1"), replicaSet("c", "5"), replicaSet("b", "3"), replicaSet("d", "6")}
+
+	tests := []struct {
+		name             string
+		revisionNumber   string
+		expectedCurrent  string
+		expectedPrevious string
+	}{
+		{
+			name:             "latest revision",
+			revisionNumber:   "6",
+			expectedCurrent:  "d",
+			expectedPrevious: "c",
+		},
+		{
+			name:             "skipped revision",
+			revisionNumber:   "5",
+			expectedCurrent:  "c",
+			expectedPrevious: "b",
+		},
+		{
+			name:            "first revision",
+			revisionNumber:  "1",
+			expectedCurrent: "a",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current, previous := currentAndPreviousReplicaSets(replicaSets, tt.revisionNumber)
+			var currentName, previousName string
+			if current != nil {
+				currentName = current.Name
+			}
+			if previous != nil {
+				previousName = previous.Name
+			}
+			if currentName != tt.expectedCurrent || previousName != tt.expectedPrevious {
+				t.Errorf("currentAndPreviousReplicaSets() = %v, %v, expectedOutput %v, %v", currentName, previousName, tt.expectedCurrent, tt.expectedPrevious)
+			}
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package kubernetes
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/uswitch/hermod/pkg/scm"
+	"github.com/uswitch/hermod/pkg/slack"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	argoCDInstanceLabel      = "argocd.argoproj.io/instance"
+	argoCDTrackingAnnotation = "argocd.argoproj.io/tracking-id"
+
+	fluxKustomizationNameLabel      = "kustomize.toolkit.fluxcd.io/name"
+	fluxKustomizationNamespaceLabel = "kustomize.toolkit.fluxcd.io/namespace"
+	fluxHelmReleaseNameLabel        = "helm.toolkit.fluxcd.io/name"
+	fluxHelmReleaseNamespaceLabel   = "helm.toolkit.fluxcd.io/namespace"
+
+	argoCDApplicationKind   = "Application"
+	fluxKustomizationKind   = "Kustomization"
+	fluxHelmReleaseKind     = "HelmRelease"
+	fluxGitRepositoryKind   = "GitRepository"
+	gitOpsRevisionCacheTime = 30 * time.Second
+)
+
+var (
+	argoCDApplications  = schema.GroupVersionResource{Group: "argoproj.io", Version: "v1alpha1", Resource: "applications"}
+	fluxKustomizations  = schema.GroupVersionResource{Group: "kustomize.toolkit.fluxcd.io", Version: "v1", Resource: "kustomizations"}
+	fluxGitRepositories = schema.GroupVersionResource{Group: "source.toolkit.fluxcd.io", Version: "v1", Resource: "gitrepositories"}
+)
+
+// GitOpsOptions configures how deployments reconciled by Argo CD or Flux are reported
+type GitOpsOptions struct {
+	// ArgoCDURL is the base URL of the Argo CD UI to link applications to, no link is added when empty
+	ArgoCDURL string
+	// ArgoCDNamespace is the namespace of the applications named by the argocd.argoproj.io/instance label
+	ArgoCDNamespace string
+	// Revisions reads the repository and revision of deployments without git annotations from their Application or Kustomization
+	Revisions bool
+}
+
+// gitOpsOwner is the Argo CD Application or Flux Kustomization or HelmRelease reconciling a deployment
+type gitOpsOwner struct {
+	kind      string
+	namespace string
+	name      string
+}
+
+// gitOpsOwner will return the Argo CD Application or Flux resource the deployment is reconciled by, if any
+func (b *deploymentInformer) gitOpsOwner(deployment *appsv1.Deployment) (gitOpsOwner, bool) {
+	// the tracking id is <application>:<group>/<kind>:<namespace>/<name>
+	application := deployment.Labels[argoCDInstanceLabel]
+	if tracking := deployment.Annotations[argoCDTrackingAnnotation]; tracking != "" {
+		application = strings.SplitN(tracking, ":", 2)[0]
+	}
+	if application != "" {
+		// applications outside the Argo CD namespace are named <namespace>_<name>
+		if parts := strings.SplitN(application, "_", 2); len(parts) == 2 {
+			return gitOpsOwner{kind: argoCDApplicationKind, namespace: parts[0], name: parts[1]}, true
+		}
+		return gitOpsOwner{kind: argoCDApplicationKind, namespace: b.GitOps.ArgoCDNamespace, name: application}, true
+	}
+
+	if name := deployment.Labels[fluxKustomizationNameLabel]; name != "" {
+		return gitOpsOwner{kind: fluxKustomizationKind, namespace: deployment.Labels[fluxKustomizationNamespaceLabel], name: name}, true
+	}
+	if name := deployment.Labels[fluxHelmReleaseNameLabel]; name != "" {
+		return gitOpsOwner{kind: fluxHelmReleaseKind, namespace: deployment.Labels[fluxHelmReleaseNamespaceLabel], name: name}, true
+	}
+
+	return gitOpsOwner{}, false
+}
+
+// context describes the owner in the context of a message
+func (o gitOpsOwner) context() string {
+	if o.kind == argoCDApplicationKind {
+		return fmt.Sprintf("*Argo CD:* `%s`", o.name)
+	}
+	return fmt.Sprintf("*Flux:* %s `%s/%s`", o.kind, o.namespace, o.name)
+}
+
+// gitOpsLink will link to the Argo CD application of the deployment, when the URL of Argo CD is set
+func (b *deploymentInformer) gitOpsLink(deployment *appsv1.Deployment) (slack.Link, bool) {
+	owner, ok := b.gitOpsOwner(deployment)
+	if !ok || owner.kind != argoCDApplicationKind || b.GitOps.ArgoCDURL == "" {
+		return slack.Link{}, false
+	}
+
+	return slack.Link{
+		Text: "Argo CD",
+		URL:  fmt.Sprintf("%s/applications/%s/%s", strings.TrimSuffix(b.GitOps.ArgoCDURL, "/"), url.PathEscape(owner.namespace), url.PathEscape(owner.name)),
+	}, true
+}
+
+// gitRevision will return the repository and sha the deployment was built from, from its git annotations or, when it
+// has none, from the status of its Argo CD Application or Flux Kustomization
+func (b *deploymentInformer) gitRevision(deployment *appsv1.Deployment) (string, string) {
+	repo := deployment.GetAnnotations()[b.HermodGithubRepoAnnotation]
+	sha := deployment.GetAnnotations()[b.HermodGithubCommitSHAAnnotation]
+	if (repo != "" && sha != "") || !b.GitOps.Revisions || b.DynamicClient == nil {
+		return repo, sha
+	}
+
+	owner, ok := b.gitOpsOwner(deployment)
+	if !ok {
+		return repo, sha
+	}
+
+	revision, err := b.gitOpsRevisions.get(owner, time.Now(), b.getGitOpsRevision)
+	if err != nil {
+		log.Warnf("failed to get source revision of %s `%s/%s` of deployment `%s` in `%s` namespace: %v", owner.kind, owner.namespace, owner.name, deployment.Name, deployment.Namespace, err)
+		return repo, sha
+	}
+
+	return revision.repo, revision.sha
+}
+
+type gitOpsRevision struct {
+	repo    string
+	sha     string
+	fetched time.Time
+}
+
+// gitOpsRevisions caches the source revisions of owners briefly, they are read several times for each message
+type gitOpsRevisions struct {
+	sync.Mutex
+	cached map[gitOpsOwner]gitOpsRevision
+}
+
+func (r *gitOpsRevisions) get(owner gitOpsOwner, now time.Time, fetch func(gitOpsOwner) (gitOpsRevision, error)) (gitOpsRevision, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if revision, ok := r.cached[owner]; ok && now.Sub(revision.fetched) < gitOpsRevisionCacheTime {
+		return revision, nil
+	}
+
+	revision, err := fetch(owner)
+	if err != nil {
+		return gitOpsRevision{}, err
+	}
+
+	if r.cached == nil {
+		r.cached = map[gitOpsOwner]gitOpsRevision{}
+	}
+	revision.fetched = now
+	r.cached[owner] = revision
+
+	return revision, nil
+}
+
+// getGitOpsRevision will read the repository and revision last synced by an Argo CD Application or applied by a Flux Kustomization
+func (b *deploymentInformer) getGitOpsRevision(owner gitOpsOwner) (gitOpsRevision, error) {
+	switch owner.kind {
+	case argoCDApplicationKind:
+		application, err := b.DynamicClient.Resource(argoCDApplications).Namespace(owner.namespace).Get(b.Context, owner.name, metav1.GetOptions{})
+		if err != nil {
+			return gitOpsRevision{}, err
+		}
+		return argoCDRevision(application)
+	case fluxKustomizationKind:
+		kustomization, err := b.DynamicClient.Resource(fluxKustomizations).Namespace(owner.namespace).Get(b.Context, owner.name, metav1.GetOptions{})
+		if err != nil {
+			return gitOpsRevision{}, err
+		}
+
+		kind, _, _ := unstructured.NestedString(kustomization.Object, "spec", "sourceRef", "kind")
+		name, _, _ := unstructured.NestedString(kustomization.Object, "spec", "sourceRef", "name")
+		namespace, _, _ := unstructured.NestedString(kustomization.Object, "spec", "sourceRef", "namespace")
+		if kind != fluxGitRepositoryKind {
+			return gitOpsRevision{}, fmt.Errorf("source %s is not a %s", kind, fluxGitRepositoryKind)
+		}
+		if namespace == "" {
+			namespace = owner.namespace
+		}
+
+		repository, err := b.DynamicClient.Resource(fluxGitRepositories).Namespace(namespace).Get(b.Context, name, metav1.GetOptions{})
+		if err != nil {
+			return gitOpsRevision{}, err
+		}
+		return fluxRevision(kustomization, repository)
+	default:
+		return gitOpsRevision{}, fmt.Errorf("revisions of %s are not supported", owner.kind)
+	}
+}
+
+// argoCDRevision is the repository and revision an Application last synced, applications of Helm charts have none
+func argoCDRevision(application *unstructured.Unstructured) (gitOpsRevision, error) {
+	repo, _, _ := unstructured.NestedString(application.Object, "spec", "source", "repoURL")
+	chart, _, _ := unstructured.NestedString(application.Object, "spec", "source", "chart")
+	sha, _, _ := unstructured.NestedString(application.Object, "status", "sync", "revision")
+
+	if sources, _, _ := unstructured.NestedSlice(application.Object, "spec", "sources"); repo == "" && len(sources) > 0 {
+		// the first source of a multi-source application
+		source, _ := sources[0].(map[string]interface{})
+		repo, _, _ = unstructured.NestedString(source, "repoURL")
+		chart, _, _ = unstructured.NestedString(source, "chart")
+		revisions, _, _ := unstructured.NestedStringSlice(application.Object, "status", "sync", "revisions")
+		if len(revisions) > 0 {
+			sha = revisions[0]
+		}
+	}
+
+	if repo == "" || sha == "" || chart != "" {
+		return gitOpsRevision{}, fmt.Errorf("application %s has not synced a git revision", application.GetName())
+	}
+
+	return gitOpsRevision{repo: scm.NormaliseRepoURL(repo), sha: sha}, nil
+}
+
+// fluxRevision is the repository and revision a Kustomization last applied, e.g. `main@sha1:<sha>` or `main/<sha>`
+func fluxRevision(kustomization, repository *unstructured.Unstructured) (gitOpsRevision, error) {
+	repo, _, _ := unstructured.NestedString(repository.Object, "spec", "url")
+	revision, _, _ := unstructured.NestedString(kustomization.Object, "status", "lastAppliedRevision")
+
+	sha := revision
+	if i := strings.LastIndex(sha, "@"); i >= 0 {
+		sha = sha[i+1:]
+	} else if i := strings.LastIndex(sha, "/"); i >= 0 {
+		sha = sha[i+1:]
+	}
+	sha = strings.TrimPrefix(sha, "sha1:")
+
+	if repo == "" || sha == "" {
+		return gitOpsRevision{}, fmt.Errorf("kustomization %s has not applied a git revision", kustomization.GetName())
+	}
+
+	return gitOpsRevision{repo: scm.NormaliseRepoURL(repo), sha: sha}, nil
+}
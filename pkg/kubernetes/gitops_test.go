@@ -0,0 +1,99 @@
+package kubernetes
+
+import (
+	"testing"
+)
+
+func TestGitOpsOwner(t *testing.T) {
+	b := &deploymentInformer{Settings: Settings{GitOps: GitOpsOptions{ArgoCDNamespace: "argocd"}}}
+
+	tests := []struct {
+		name           string
+		annotations    map[string]string
+		labels         map[string]string
+		expectedOutput gitOpsOwner
+		expectedFound  bool
+	}{
+		{
+			name:           "argo cd instance label",
+			labels:         map[string]string{"argocd.argoproj.io/instance": "payments-api"},
+			expectedOutput: gitOpsOwner{kind: "Application", namespace: "argocd", name: "payments-api"},
+			expectedFound:  true,
+		},
+		{
+			name:           "argo cd tracking id of an application in another namespace",
+			annotations:    map[string]string{"argocd.argoproj.io/tracking-id": "payments_api:apps/Deployment:payments/api"},
+			expectedOutput: gitOpsOwner{kind: "Application", namespace: "payments", name: "api"},
+			expectedFound:  true,
+		},
+		{
+			name:           "flux kustomization",
+			labels:         map[string]string{"kustomize.toolkit.fluxcd.io/name": "apps", "kustomize.toolkit.fluxcd.io/namespace": "flux-system"},
+			expectedOutput: gitOpsOwner{kind: "Kustomization", namespace: "flux-system", name: "apps"},
+			expectedFound:  true,
+		},
+		{
+			name:           "flux helm release",
+			labels:         map[string]string{"helm.toolkit.fluxcd.io/name": "api", "helm.toolkit.fluxcd.io/namespace": "payments"},
+			expectedOutput: gitOpsOwner{kind: "HelmRelease", namespace: "payments", name: "api"},
+			expectedFound:  true,
+		},
+		{
+			name: "not reconciled by gitops",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deployment := testDeployment("", tt.annotations, tt.labels)
+			output, found := b.gitOpsOwner(deployment)
+			if output != tt.expectedOutput || found != tt.expectedFound {
+				t.Errorf("gitOpsOwner() = %v, %v, expectedOutput %v, %v", output, found, tt.expectedOutput, tt.expectedFound)
+			}
+		})
+	}
+}
+
+func TestGitRevision(t *testing.T) {
+	b := newGitOpsInformer()
+
+	tests := []struct {
+		name         string
+		annotations  map[string]string
+		labels       map[string]string
+		expectedRepo string
+		expectedSHA  string
+	}{
+		{
+			name:         "git annotations",
+			annotations:  map[string]string{"hermod.uswitch.com/gitrepo": "https://github.com/uswitch/web", "hermod.uswitch.com/gitsha": "abc123"},
+			labels:       map[string]string{"argocd.argoproj.io/instance": "payments-api"},
+			expectedRepo: "https://github.com/uswitch/web",
+			expectedSHA:  "abc123",
+		},
+		{
+			name:         "argo cd application",
+			labels:       map[string]string{"argocd.argoproj.io/instance": "payments-api"},
+			expectedRepo: "https://github.com/uswitch/api",
+			expectedSHA:  "2dafeb708437f6e537d19556d461e30aa96d4244",
+		},
+		{
+			name:         "flux kustomization",
+			labels:       map[string]string{"kustomize.toolkit.fluxcd.io/name": "apps", "kustomize.toolkit.fluxcd.io/namespace": "flux-system"},
+			expectedRepo: "https://github.com/uswitch/apps",
+			expectedSHA:  "5f4c3b2a1908d7e6f5a4b3c2d1e0f9a8b7c6d5e4",
+		},
+		{
+			name:   "missing application",
+			labels: map[string]string{"argocd.argoproj.io/instance": "search-api"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deployment := testDeployment("", tt.annotations, tt.labels)
+			repo, sha := b.gitRevision(deployment)
+			if repo != tt.expectedRepo || sha != tt.expectedSHA {
+				t.Errorf("gitRevision() = %v, %v, expectedOutput %v, %v", repo, sha, tt.expectedRepo, tt.expectedSHA)
+			}
+		})
+	}
+}
@@ -12,7 +12,48 @@ import (
 	"k8s.io/client-go/kubernetes"
 )
 
-func getErrorEvents(ctx context.Context, client kubernetes.Interface, namespace string, newDeployment *appsv1.Deployment) (string, error) {
+// rolloutError is an error found on the pods of a rollout, or on its replicaset when there are no pods
+type rolloutError struct {
+	reason  string
+	message string
+}
+
+func (e rolloutError) String() string {
+	if e.reason == "" {
+		return fmt.Sprintf("```%v```", e.message)
+	}
+	return fmt.Sprintf("```\n* %s - %s\n```", e.reason, e.message)
+}
+
+// failureSummary describes why the rollout failed in a sentence
+func failureSummary(newDeployment *appsv1.Deployment, rs appsv1.ReplicaSet, errorList []rolloutError) string {
+	// delayed rollout
+	if len(errorList) == 0 {
+		return fmt.Sprintf("*Deployment `%s` (RS: `%s`) in `%s` namespace failed to reach desired replicas within `%v` seconds on the `%s` cluster, only `%v/%v` replicas are ready.*", newDeployment.Name, rs.Name, newDeployment.Namespace, *newDeployment.Spec.ProgressDeadlineSeconds, getClusterName(), newDeployment.Status.ReadyReplicas, *newDeployment.Spec.Replicas)
+	}
+
+	// errored rollout
+	return fmt.Sprintf("*Rollout for Deployment `%s` (RS: `%s`) in `%s` namespace failed after `%v` seconds on the `%s` cluster.*", newDeployment.Name, rs.Name, newDeployment.Namespace, *newDeployment.Spec.ProgressDeadlineSeconds, getClusterName())
+}
+
+// formatErrorEvents describes the failed rollout and lists its errors
+func formatErrorEvents(newDeployment *appsv1.Deployment, rs appsv1.ReplicaSet, errorList []rolloutError) string {
+	errorText := failureSummary(newDeployment, rs, errorList)
+	if len(errorList) == 0 {
+		return errorText + "\n"
+	}
+
+	// construct error message
+	errorString := []string{errorText + "\n\n*Retrieved the following errors:*"}
+	for _, e := range errorList {
+		errorString = append(errorString, e.String())
+	}
+
+	return strings.Join(errorString, "\n")
+}
+
+// getPodErrors will return the current replicaset of the deployment and the errors of its pods
+func getPodErrors(ctx context.Context, client kubernetes.Interface, namespace string, newDeployment *appsv1.Deployment) (appsv1.ReplicaSet, []rolloutError, error) {
 
 	// Get Pod Labels
 	podLabels := newDeployment.Spec.Template.Labels
@@ -22,7 +63,7 @@ func getErrorEvents(ctx context.Context, client kubernetes.Interface, namespace
 	// Find Replicaset based on labels and given revision in annotation
 	rs, err := getReplicaSet(ctx, client, namespace, labelSelector, newDeployment.Annotations[revision])
 	if err != nil {
-		return fmt.Sprintf("failed to get the replicaset: %v", err), err
+		return rs, nil, fmt.Errorf("failed to get the replicaset: %v", err)
 	}
 
 	// Get Replicaset Labels
@@ -32,53 +73,56 @@ func getErrorEvents(ctx context.Context, client kubernetes.Interface, namespace
 	// Get list of Pods based on ReplicaSet labels
 	pods, err := getPods(ctx, client, namespace, labelSelector)
 	if err != nil {
-		return fmt.Sprintf("failed to get the pods: %v", err), err
+		return rs, nil, fmt.Errorf("failed to get the pods: %v", err)
 	}
 
 	// Get errors from Replicaset
-	var errorList []string
+	var errorList []rolloutError
 
 	if len(pods) == 0 {
 		rsConditions := rs.Status.Conditions
 		sort.Slice(rsConditions, func(i, j int) bool {
 			return rsConditions[i].LastTransitionTime.Before(&rsConditions[j].LastTransitionTime)
 		})
-		errorList = append(errorList, fmt.Sprintf("```%v```", rsConditions[len(rsConditions)-1].Message))
+		if len(rsConditions) > 0 {
+			errorList = append(errorList, rolloutError{message: rsConditions[len(rsConditions)-1].Message})
+		}
 	} else {
-		// Map is to avoid duplicate errors
+		// Map is to avoid duplicate errors, reasons keeps the order they were found in
 		reasonMessageMap := make(map[string]string)
+		var reasons []string
+		addReasons := func(found map[string]string) {
+			for reason, message := range found {
+				if _, ok := reasonMessageMap[reason]; !ok {
+					reasons = append(reasons, reason)
+				}
+				reasonMessageMap[reason] = message
+			}
+		}
+
 		for _, pod := range pods {
 			// look for error message in init Containers
-			reasonMessageMap = getReasonMessageMapFromStatuses(pod.Status.InitContainerStatuses, reasonMessageMap)
+			for _, status := range pod.Status.InitContainerStatuses {
+				addReasons(getReasonMessageMapFromStatuses([]corev1.ContainerStatus{status}, map[string]string{}))
+			}
 
 			// look for error message in Containers
-			reasonMessageMap = getReasonMessageMapFromStatuses(pod.Status.ContainerStatuses, reasonMessageMap)
+			for _, status := range pod.Status.ContainerStatuses {
+				addReasons(getReasonMessageMapFromStatuses([]corev1.ContainerStatus{status}, map[string]string{}))
+			}
 
 			// look for error message in Pod Conditions
-			reasonMessageMap = getReasonMessageMapFromPodConditions(pod.Status.Conditions, reasonMessageMap)
+			for _, condition := range pod.Status.Conditions {
+				addReasons(getReasonMessageMapFromPodConditions([]corev1.PodCondition{condition}, map[string]string{}))
+			}
 		}
 
-		for reason, message := range reasonMessageMap {
-			errorList = append(errorList, fmt.Sprintf("```\n* %s - %s\n```", reason, message))
+		for _, reason := range reasons {
+			errorList = append(errorList, rolloutError{reason: reason, message: reasonMessageMap[reason]})
 		}
 	}
 
-	// construct error message
-	var errorString []string
-
-	// delayed rollout
-	if len(errorList) == 0 {
-		errorText := fmt.Sprintf("*Deployment `%s` (RS: `%s`) in `%s` namespace failed to reach desired replicas within `%v` seconds on the `%s` cluster, only `%v/%v` replicas are ready.*\n", newDeployment.Name, rs.Name, newDeployment.Namespace, *newDeployment.Spec.ProgressDeadlineSeconds, getClusterName(), newDeployment.Status.ReadyReplicas, *newDeployment.Spec.Replicas)
-		return errorText, nil
-	}
-
-	// errored rollout
-	errorText := fmt.Sprintf("*Rollout for Deployment `%s` (RS: `%s`) in `%s` namespace failed after `%v` seconds on the `%s` cluster.*\n\n*Retrieved the following errors:*", newDeployment.Name, rs.Name, newDeployment.Namespace, *newDeployment.Spec.ProgressDeadlineSeconds, getClusterName())
-
-	errorString = append(errorString, errorText)
-	errorString = append(errorString, errorList...)
-
-	return strings.Join(errorString, "\n"), nil
+	return rs, errorList, nil
 }
 
 func getReasonMessageMapFromPodConditions(conditions []corev1.PodCondition, reasonMessageMap map[string]string) map[string]string {
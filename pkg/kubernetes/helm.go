@@ -0,0 +1,210 @@
+package kubernetes
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/uswitch/hermod/pkg/templates"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// set by Helm 3 on every resource of a release
+	helmReleaseNameAnnotation = "meta.helm.sh/release-name"
+
+	helmReleaseSecretType = "helm.sh/release.v1"
+
+	// how long the release of a rollout is cached, it is read several times for each message
+	helmReleaseCacheTime = 30 * time.Second
+)
+
+// gzip header Helm checks for before decompressing a release
+var gzipMagic = []byte{0x1f, 0x8b, 0x08}
+
+// helmRelease is the part of a Helm release, as stored in its sh.helm.release.v1.<name>.v<revision> secrets, Hermod reports
+type helmRelease struct {
+	Name    string `json:"name"`
+	Version int    `json:"version"`
+	Info    struct {
+		Status string `json:"status"`
+	} `json:"info"`
+	Chart struct {
+		Metadata struct {
+			Name       string `json:"name"`
+			Version    string `json:"version"`
+			AppVersion string `json:"appVersion"`
+		} `json:"metadata"`
+	} `json:"chart"`
+}
+
+// helmReleaseName will return the name of the Helm release the deployment was installed by, if any
+func helmReleaseName(deployment *appsv1.Deployment) string {
+	if name := deployment.Annotations[helmReleaseNameAnnotation]; name != "" {
+		return name
+	}
+
+	if deployment.Labels[helmManagedByLabel] == "Helm" && deployment.Labels[helmInstanceLabel] != "" {
+		return deployment.Labels[helmInstanceLabel]
+	}
+	if deployment.Labels[helmHeritageLabel] == "Helm" && deployment.Labels[helmReleaseLabel] != "" {
+		return deployment.Labels[helmReleaseLabel]
+	}
+
+	return ""
+}
+
+// helmRelease will return the latest revision of the Helm release the deployment was installed by, it is nil when
+// Helm releases are not read or the deployment was not installed by Helm
+func (b *deploymentInformer) helmRelease(deployment *appsv1.Deployment) *helmRelease {
+	if !b.HelmReleases {
+		return nil
+	}
+
+	name := helmReleaseName(deployment)
+	if name == "" {
+		return nil
+	}
+
+	ref := helmReleaseRef{namespace: deployment.Namespace, name: name, deployment: deployment.Name, revision: deployment.Annotations[revision]}
+	release, err := b.helmReleaseCache.get(ref, time.Now(), func(ref helmReleaseRef) (*helmRelease, error) {
+		return getHelmRelease(b.Context, b.client, ref.namespace, ref.name)
+	})
+	if err != nil {
+		log.Warnf("failed to get helm release of deployment `%s` in `%s` namespace: %v", deployment.Name, deployment.Namespace, err)
+		return nil
+	}
+
+	return release
+}
+
+// helmReleaseRef is the Helm release of a revision of a deployment, a new revision is rolled out by a new release revision
+type helmReleaseRef struct {
+	namespace  string
+	name       string
+	deployment string
+	revision   string
+}
+
+type cachedHelmRelease struct {
+	release *helmRelease
+	fetched time.Time
+}
+
+// helmReleaseCache caches the Helm release of each rollout briefly, listing the release secrets returns every stored
+// revision with its manifest
+type helmReleaseCache struct {
+	sync.Mutex
+	cached map[helmReleaseRef]cachedHelmRelease
+}
+
+func (c *helmReleaseCache) get(ref helmReleaseRef, now time.Time, fetch func(helmReleaseRef) (*helmRelease, error)) (*helmRelease, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	for key, cached := range c.cached {
+		if now.Sub(cached.fetched) >= helmReleaseCacheTime {
+			delete(c.cached, key)
+		}
+	}
+
+	if cached, ok := c.cached[ref]; ok {
+		return cached.release, nil
+	}
+
+	release, err := fetch(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.cached == nil {
+		c.cached = map[helmReleaseRef]cachedHelmRelease{}
+	}
+	c.cached[ref] = cachedHelmRelease{release: release, fetched: now}
+
+	return release, nil
+}
+
+// getHelmRelease will decode the latest revision of a Helm release from its release secrets
+func getHelmRelease(ctx context.Context, client kubernetes.Interface, namespace, name string) (*helmRelease, error) {
+	secrets, err := client.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.Set{"owner": "helm", "name": name}.String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list release secrets: %v", err)
+	}
+
+	var latest *corev1.Secret
+	latestVersion := 0
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		version, err := strconv.Atoi(secret.Labels["version"])
+		if secret.Type != helmReleaseSecretType || err != nil {
+			continue
+		}
+		if version > latestVersion {
+			latest, latestVersion = secret, version
+		}
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("no release secret of helm release %s", name)
+	}
+
+	return decodeHelmRelease(latest.Data["release"])
+}
+
+// decodeHelmRelease will decode the release of a release secret, Helm stores it as base64 encoded and gzipped JSON
+func decodeHelmRelease(data []byte) (*helmRelease, error) {
+	decoded, err := base64.StdEncoding.DecodeString(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode helm release: %v", err)
+	}
+
+	if bytes.HasPrefix(decoded, gzipMagic) {
+		reader, err := gzip.NewReader(bytes.NewReader(decoded))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress helm release: %v", err)
+		}
+		defer reader.Close()
+
+		decoded, err = io.ReadAll(reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress helm release: %v", err)
+		}
+	}
+
+	var release helmRelease
+	if err := json.Unmarshal(decoded, &release); err != nil {
+		return nil, fmt.Errorf("failed to parse helm release: %v", err)
+	}
+
+	return &release, nil
+}
+
+// context describes the release in the context of a message
+func (r *helmRelease) context() string {
+	return fmt.Sprintf("*Helm release:* `%s` revision %d, chart `%s-%s`, app `%s`", r.Name, r.Version, r.Chart.Metadata.Name, r.Chart.Metadata.Version, r.Chart.Metadata.AppVersion)
+}
+
+// event describes the release for message templates
+func (r *helmRelease) event() templates.Helm {
+	return templates.Helm{
+		Release:      r.Name,
+		Revision:     r.Version,
+		Chart:        r.Chart.Metadata.Name,
+		ChartVersion: r.Chart.Metadata.Version,
+		AppVersion:   r.Chart.Metadata.AppVersion,
+	}
+}
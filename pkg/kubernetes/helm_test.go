@@ -0,0 +1,150 @@
+package kubernetes
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+)
+
+func helmReleaseSecret(name string, version int, status, chartVersion string) *corev1.Secret {
+	release := fmt.Sprintf(`{"name": %q, "version": %d, "info": {"status": %q}, "chart": {"metadata": {"name": "api", "version": %q, "appVersion": "v%s"}}}`, name, version, status, chartVersion, chartVersion)
+
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	writer.Write([]byte(release))
+	writer.Close()
+
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("sh.helm.release.v1.%s.v%d", name, version),
+			Namespace: "payments",
+			Labels:    map[string]string{"owner": "helm", "name": name, "version": fmt.Sprint(version), "status": status},
+		},
+		Type: helmReleaseSecretType,
+		Data: map[string][]byte{"release": []byte(base64.StdEncoding.EncodeToString(compressed.Bytes()))},
+	}
+}
+
+func TestGetHelmRelease(t *testing.T) {
+	tests := []struct {
+		name            string
+		secrets         []runtime.Object
+		expectedVersion int
+		expectedChart   string
+		expectError     bool
+	}{
+		{
+			name: "latest revision",
+			secrets: []runtime.Object{
+				helmReleaseSecret("api", 9, "superseded", "1.1.0"),
+				helmReleaseSecret("api", 10, "deployed", "1.2.0"),
+				helmReleaseSecret("worker", 11, "deployed", "2.0.0"),
+			},
+			expectedVersion: 10,
+			expectedChart:   "1.2.0",
+		},
+		{
+			name:        "no release",
+			secrets:     []runtime.Object{helmReleaseSecret("worker", 1, "deployed", "2.0.0")},
+			expectError: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := k8sfake.NewSimpleClientset(tt.secrets...)
+			release, err := getHelmRelease(context.Background(), client, "payments", "api")
+			if (err != nil) != tt.expectError {
+				t.Fatalf("getHelmRelease() error = %v, expectError %v", err, tt.expectError)
+			}
+			if err != nil {
+				return
+			}
+			if release.Version != tt.expectedVersion || release.Chart.Metadata.Version != tt.expectedChart {
+				t.Errorf("getHelmRelease() = %v, %v, expectedOutput %v, %v", release.Version, release.Chart.Metadata.Version, tt.expectedVersion, tt.expectedChart)
+			}
+		})
+	}
+}
+
+func TestHelmReleaseCache(t *testing.T) {
+	client := k8sfake.NewSimpleClientset(helmReleaseSecret("api", 10, "deployed", "1.2.0"))
+	b := &deploymentInformer{Context: context.Background(), client: client, Settings: Settings{HelmReleases: true}}
+	now := time.Now()
+	deployment := func(revisionNumber string) *appsv1.Deployment {
+		return &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "payments", Annotations: map[string]string{
+			helmReleaseNameAnnotation: "api",
+			revision:                  revisionNumber,
+		}}}
+	}
+	lists := func() int {
+		count := 0
+		for _, action := range client.Actions() {
+			if action.GetVerb() == "list" && action.GetResource().Resource == "secrets" {
+				count++
+			}
+		}
+		return count
+	}
+
+	for i := 0; i < 3; i++ {
+		if release := b.helmRelease(deployment("4")); release == nil || release.Version != 10 {
+			t.Fatalf("helmRelease() = %v, expected revision 10", release)
+		}
+	}
+	if lists() != 1 {
+		t.Errorf("helmRelease() listed the release secrets %d times, expected once for the rollout", lists())
+	}
+
+	client.Tracker().Add(helmReleaseSecret("api", 11, "deployed", "1.3.0"))
+	if release := b.helmRelease(deployment("5")); release == nil || release.Version != 11 {
+		t.Errorf("helmRelease() = %v, expected revision 11 for the next rollout", release)
+	}
+
+	if _, err := b.helmReleaseCache.get(helmReleaseRef{namespace: "payments", name: "api", deployment: "api", revision: "5"}, now.Add(time.Minute), func(helmReleaseRef) (*helmRelease, error) {
+		return nil, fmt.Errorf("fetched")
+	}); err == nil {
+		t.Errorf("get() returned a release cached for longer than %s", helmReleaseCacheTime)
+	}
+}
+
+func TestHelmReleaseName(t *testing.T) {
+	tests := []struct {
+		name           string
+		annotations    map[string]string
+		labels         map[string]string
+		expectedOutput string
+	}{
+		{
+			name:           "helm 3 annotation",
+			annotations:    map[string]string{"meta.helm.sh/release-name": "api"},
+			expectedOutput: "api",
+		},
+		{
+			name:           "recommended labels",
+			labels:         map[string]string{"app.kubernetes.io/managed-by": "Helm", "app.kubernetes.io/instance": "api"},
+			expectedOutput: "api",
+		},
+		{
+			name:   "not installed by helm",
+			labels: map[string]string{"app.kubernetes.io/instance": "api"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deployment := testDeployment("", tt.annotations, tt.labels)
+			if output := helmReleaseName(deployment); output != tt.expectedOutput {
+				t.Errorf("helmReleaseName() = %v, expectedOutput %v", output, tt.expectedOutput)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/robfig/cron/v3"
+	log "github.com/sirupsen/logrus"
+	"github.com/uswitch/hermod/pkg/slack"
+	"github.com/uswitch/hermod/pkg/templates"
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+const (
+	hermodMuteUntilAnnotation         = "hermod.uswitch.com/mute-until"
+	hermodMaintenanceWindowAnnotation = "hermod.uswitch.com/maintenance-window"
+
+	// maximum number of deployments listed in a maintenance summary
+	maxSummaryDeployments = 50
+	// how far ahead a window ends at most, the occurrences of a schedule such as `0 * * * * 4h` never stop overlapping
+	maxMaintenanceWindow = 7 * 24 * time.Hour
+)
+
+var mutedNotificationTotal = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "hermod_notifications_muted_total",
+	Help: "The total number of notifications not sent during maintenance windows",
+})
+
+// MaintenanceWindow is a recurring period during which only failures are notified
+type MaintenanceWindow struct {
+	schedule cron.Schedule
+	duration time.Duration
+}
+
+// ParseMaintenanceWindow parses a cron schedule followed by the length of the window, e.g. `0 2 * * SAT 4h`.
+// The schedule is in UTC unless it starts with a time zone, e.g. `CRON_TZ=Europe/London 0 2 * * SAT 4h`.
+func ParseMaintenanceWindow(value string) (MaintenanceWindow, error) {
+	fields := strings.Fields(value)
+	if len(fields) < 2 {
+		return MaintenanceWindow{}, fmt.Errorf("maintenance window %q is not a cron schedule followed by a duration", value)
+	}
+
+	duration, err := time.ParseDuration(fields[len(fields)-1])
+	if err != nil || duration <= 0 {
+		return MaintenanceWindow{}, fmt.Errorf("maintenance window %q does not end with a positive duration", value)
+	}
+
+	schedule, err := cron.ParseStandard(strings.Join(fields[:len(fields)-1], " "))
+	if err != nil {
+		return MaintenanceWindow{}, fmt.Errorf("maintenance window %q has an invalid schedule: %v", value, err)
+	}
+
+	return MaintenanceWindow{schedule: schedule, duration: duration}, nil
+}
+
+// end returns when the window now falls in ends, if it falls in one. Occurrences of the schedule which overlap
+// or follow each other make one window, which ends with the last of them.
+func (w MaintenanceWindow) end(now time.Time) (time.Time, bool) {
+	start := w.schedule.Next(now.Add(-w.duration))
+	if start.After(now) {
+		return time.Time{}, false
+	}
+
+	end := start.Add(w.duration)
+	for next := w.schedule.Next(start); !next.IsZero() && !next.After(end) && end.Sub(now) < maxMaintenanceWindow; next = w.schedule.Next(next) {
+		end = next.Add(w.duration)
+	}
+
+	return end, true
+}
+
+// mutedUntil will return the end of the maintenance window the deployment is in, from its mute-until
+// and maintenance-window settings, those of its namespace and the cluster maintenance windows
+func (b *deploymentInformer) mutedUntil(deployment *appsv1.Deployment, now time.Time) (time.Time, bool) {
+	var until time.Time
+	extend := func(end time.Time) {
+		if end.After(now) && end.After(until) {
+			until = end
+		}
+	}
+
+	if value, err := b.getSetting(deployment, hermodMuteUntilAnnotation); err == nil && value != "" {
+		end, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			log.Warnf("invalid %s annotation for deployment `%s` in `%s` namespace: %v", hermodMuteUntilAnnotation, deployment.Name, deployment.Namespace, err)
+		}
+		extend(end)
+	}
+
+	windows := append(append([]MaintenanceWindow{}, b.MaintenanceWindows...), b.policies.maintenanceWindows()...)
+	if value, err := b.getSetting(deployment, hermodMaintenanceWindowAnnotation); err == nil && value != "" {
+		window, err := ParseMaintenanceWindow(value)
+		if err != nil {
+			log.Warnf("invalid %s annotation for deployment `%s` in `%s` namespace: %v", hermodMaintenanceWindowAnnotation, deployment.Name, deployment.Namespace, err)
+		} else {
+			windows = append([]MaintenanceWindow{window}, windows...)
+		}
+	}
+
+	for _, window := range windows {
+		if end, ok := window.end(now); ok {
+			extend(end)
+		}
+	}
+
+	return until, !until.IsZero()
+}
+
+// notify will send the message to the channels of the outcome, unless the deployment is in a maintenance window.
+// Failures are always sent, muted messages are kept for the summary at the end of the window when enabled.
+func (b *deploymentInformer) notify(routes slackRoutes, outcome string, deployment *appsv1.Deployment, message slack.Message) {
+	until, muted := b.mutedUntil(deployment, time.Now())
+	if !muted || outcome == templates.PhaseFailed {
+		b.sendOrCollapse(routes, outcome, deployment, message)
+		return
+	}
+
+	log.Debugf("not sending %s message for deployment `%s` in `%s` namespace during maintenance window until %s", outcome, deployment.Name, deployment.Namespace, until.Format(time.RFC3339))
+	mutedNotificationTotal.Inc()
+
+	if b.MaintenanceSummary {
+		for _, channel := range routes[outcome] {
+			b.mutedSummaries.add(channel, until, fmt.Sprintf("%s/%s", deployment.Namespace, deployment.Name), outcome)
+		}
+	}
+}
+
+// mutedSummaries keeps the outcomes of the rollouts muted in each channel until the end of their window
+type mutedSummaries struct {
+	sync.Mutex
+	pending map[mutedWindow]*mutedSummary
+}
+
+type mutedWindow struct {
+	channel string
+	end     time.Time
+}
+
+type mutedSummary struct {
+	deployments []string
+	outcomes    map[string]string
+}
+
+func (s *mutedSummaries) add(channel string, end time.Time, deployment, outcome string) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.pending == nil {
+		s.pending = map[mutedWindow]*mutedSummary{}
+	}
+
+	key := mutedWindow{channel: channel, end: end}
+	summary, ok := s.pending[key]
+	if !ok {
+		summary = &mutedSummary{outcomes: map[string]string{}}
+		s.pending[key] = summary
+	}
+	if _, ok := summary.outcomes[deployment]; !ok {
+		summary.deployments = append(summary.deployments, deployment)
+	}
+	summary.outcomes[deployment] = outcome
+}
+
+// ended will remove and return the summaries of the windows which ended by now
+func (s *mutedSummaries) ended(now time.Time) map[mutedWindow]*mutedSummary {
+	s.Lock()
+	defer s.Unlock()
+
+	ended := map[mutedWindow]*mutedSummary{}
+	for key, summary := range s.pending {
+		if !key.end.After(now) {
+			ended[key] = summary
+			delete(s.pending, key)
+		}
+	}
+
+	return ended
+}
+
+// message summarises the rollouts muted during a window
+func (s *mutedSummary) message(end time.Time) slack.Message {
+	deployments := append([]string{}, s.deployments...)
+	sort.Strings(deployments)
+
+	color := slack.GreenColor
+	var lines []string
+	for i, deployment := range deployments {
+		outcome := s.outcomes[deployment]
+		if outcome != templates.PhaseSucceeded {
+			color = slack.OrangeColor
+		}
+		if i < maxSummaryDeployments {
+			lines = append(lines, fmt.Sprintf("• `%s` %s", deployment, outcome))
+		}
+	}
+	if len(deployments) > maxSummaryDeployments {
+		lines = append(lines, fmt.Sprintf("_...and %d more_", len(deployments)-maxSummaryDeployments))
+	}
+
+	return slack.Message{
+		Header:   "Maintenance window summary",
+		Summary:  fmt.Sprintf("*%d deployments rolled out on `%s` cluster during the maintenance window that ended at %s.*", len(deployments), getClusterName(), end.UTC().Format(time.RFC3339)),
+		Sections: []string{strings.Join(lines, "\n")},
+		Color:    color,
+	}
+}
+
+// sendMaintenanceSummaries will send the summaries of ended maintenance windows every minute
+func (b *deploymentInformer) sendMaintenanceSummaries(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for window, summary := range b.mutedSummaries.ended(now) {
+				b.sendSlackMessage(slackRoutes{templates.PhaseSucceeded: {window.channel}}, templates.PhaseSucceeded, summary.message(window.end))
+			}
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package kubernetes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/uswitch/hermod/pkg/slack"
+)
+
+func TestMaintenanceWindowEnd(t *testing.T) {
+	tests := []struct {
+		name        string
+		window      string
+		now         time.Time
+		expectedEnd time.Time
+		expectedOK  bool
+	}{
+		{
+			name:        "within the window",
+			window:      "0 2 * * SAT 4h",
+			now:         time.Date(2021, 6, 5, 3, 30, 0, 0, time.UTC),
+			expectedEnd: time.Date(2021, 6, 5, 6, 0, 0, 0, time.UTC),
+			expectedOK:  true,
+		},
+		{
+			name:   "after the window",
+			window: "0 2 * * SAT 4h",
+			now:    time.Date(2021, 6, 5, 6, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "another day",
+			window: "0 2 * * SAT 4h",
+			now:    time.Date(2021, 6, 4, 3, 30, 0, 0, time.UTC),
+		},
+		{
+			name:        "overlapping occurrences",
+			window:      "0 2,4 * * SAT 4h",
+			now:         time.Date(2021, 6, 5, 5, 0, 0, 0, time.UTC),
+			expectedEnd: time.Date(2021, 6, 5, 8, 0, 0, 0, time.UTC),
+			expectedOK:  true,
+		},
+		{
+			name:        "overlapping occurrences before the second starts",
+			window:      "0 2,4 * * SAT 4h",
+			now:         time.Date(2021, 6, 5, 3, 0, 0, 0, time.UTC),
+			expectedEnd: time.Date(2021, 6, 5, 8, 0, 0, 0, time.UTC),
+			expectedOK:  true,
+		},
+		{
+			name:        "back to back occurrences",
+			window:      "0 2,6 * * SAT 4h",
+			now:         time.Date(2021, 6, 5, 3, 30, 0, 0, time.UTC),
+			expectedEnd: time.Date(2021, 6, 5, 10, 0, 0, 0, time.UTC),
+			expectedOK:  true,
+		},
+		{
+			name:        "occurrences which never stop overlapping",
+			window:      "0 * * * * 4h",
+			now:         time.Date(2021, 6, 5, 3, 30, 0, 0, time.UTC),
+			expectedEnd: time.Date(2021, 6, 12, 4, 0, 0, 0, time.UTC),
+			expectedOK:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			window, err := ParseMaintenanceWindow(tt.window)
+			if err != nil {
+				t.Fatalf("ParseMaintenanceWindow() error = %v", err)
+			}
+			end, ok := window.end(tt.now)
+			if !end.Equal(tt.expectedEnd) || ok != tt.expectedOK {
+				t.Errorf("end() = %v, %v, expectedOutput %v, %v", end, ok, tt.expectedEnd, tt.expectedOK)
+			}
+		})
+	}
+}
+
+func TestParseMaintenanceWindow(t *testing.T) {
+	tests := []struct {
+		value       string
+		expectError bool
+	}{
+		{value: "0 2 * * SAT 4h"},
+		{value: "CRON_TZ=Europe/London 0 22 * * * 8h"},
+		{value: "0 2 * * SAT", expectError: true},
+		{value: "0 2 * * MONDAY 4h", expectError: true},
+		{value: "4h", expectError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			_, err := ParseMaintenanceWindow(tt.value)
+			if (err != nil) != tt.expectError {
+				t.Errorf("ParseMaintenanceWindow() error = %v, expectError %v", err, tt.expectError)
+			}
+		})
+	}
+}
+
+func TestMutedSummaries(t *testing.T) {
+	end := time.Date(2021, 6, 5, 6, 0, 0, 0, time.UTC)
+	summaries := mutedSummaries{}
+	summaries.add("deploys", end, "payments/api", "started")
+	summaries.add("deploys", end, "payments/worker", "started")
+	summaries.add("deploys", end, "payments/api", "succeeded")
+
+	if ended := summaries.ended(end.Add(-time.Minute)); len(ended) != 0 {
+		t.Fatalf("ended() = %v before the end of the window", ended)
+	}
+
+	ended := summaries.ended(end)
+	summary, ok := ended[mutedWindow{channel: "deploys", end: end}]
+	if !ok {
+		t.Fatalf("ended() = %v, expected the summary of deploys", ended)
+	}
+
+	message := summary.message(end)
+	expectedSection := "• `payments/api` succeeded\n• `payments/worker` started"
+	if message.Sections[0] != expectedSection || message.Color != slack.OrangeColor {
+		t.Errorf("message() = %v, %v, expectedOutput %v, %v", message.Sections[0], message.Color, expectedSection, slack.OrangeColor)
+	}
+	if remaining := summaries.ended(end.Add(time.Hour)); len(remaining) != 0 {
+		t.Errorf("ended() = %v, expected summaries to be sent once", remaining)
+	}
+}
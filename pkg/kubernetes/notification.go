@@ -0,0 +1,194 @@
+package kubernetes
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	log "github.com/sirupsen/logrus"
+	"github.com/uswitch/hermod/pkg/scm"
+	"github.com/uswitch/hermod/pkg/slack"
+	"github.com/uswitch/hermod/pkg/templates"
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+const (
+	hermodRolloutStartedAnnotation = "hermod.uswitch.com/rollout-started"
+)
+
+// notification lays out a Slack message about the rollout of a deployment
+func (b *deploymentInformer) notification(deployment *appsv1.Deployment, header, summary, color string) slack.Message {
+	context := rolloutContext(deployment)
+	if helm := b.helmRelease(deployment); helm != nil {
+		context = append(context, helm.context())
+	}
+	if owner, ok := b.gitOpsOwner(deployment); ok {
+		context = append(context, owner.context())
+	}
+
+	return slack.Message{
+		Header:   header,
+		Summary:  summary,
+		Context:  context,
+		Sections: b.describeRollout(deployment),
+		Links:    b.rolloutLinks(deployment),
+		Color:    color,
+	}
+}
+
+// rolloutContext is the cluster, namespace and revision of a deployment
+func rolloutContext(deployment *appsv1.Deployment) []string {
+	var context []string
+	if cluster := getClusterName(); cluster != "" {
+		context = append(context, fmt.Sprintf("*Cluster:* `%s`", cluster))
+	}
+	context = append(context, fmt.Sprintf("*Namespace:* `%s`", deployment.Namespace))
+	if rev := deployment.Annotations[revision]; rev != "" {
+		context = append(context, fmt.Sprintf("*Revision:* `%s`", rev))
+	}
+
+	return context
+}
+
+// rolloutFields is the number of ready replicas and, when the start was recorded, how long the rollout took
+func rolloutFields(deployment *appsv1.Deployment, now time.Time) []slack.Field {
+	var replicas int32 = 1
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+
+	fields := []slack.Field{
+		{Name: "Replicas", Value: fmt.Sprintf("`%d/%d` ready", deployment.Status.ReadyReplicas, replicas)},
+	}
+
+	if duration, ok := rolloutDuration(deployment, now); ok {
+		fields = append(fields, slack.Field{Name: "Duration", Value: duration.String()})
+	}
+
+	return fields
+}
+
+// rolloutDuration is the time since Hermod saw the rollout start
+func rolloutDuration(deployment *appsv1.Deployment, now time.Time) (time.Duration, bool) {
+	started, err := time.Parse(time.RFC3339, deployment.Annotations[hermodRolloutStartedAnnotation])
+	if err != nil || now.Before(started) {
+		return 0, false
+	}
+
+	return now.Sub(started).Round(time.Second), true
+}
+
+// rolloutLinks links to the commit being rolled out, its pull request and the Argo CD application deploying it
+func (b *deploymentInformer) rolloutLinks(deployment *appsv1.Deployment) []slack.Link {
+	var links []slack.Link
+	if provider, repo, sha, ok := b.rolloutProvider(deployment); ok {
+		links = append(links,
+			slack.Link{Text: "Commit", URL: provider.CommitURL(repo, sha)},
+			slack.Link{Text: provider.MergeRequestName(), URL: provider.MergeRequestSearchURL(repo, sha)},
+		)
+	}
+	if link, ok := b.gitOpsLink(deployment); ok {
+		links = append(links, link)
+	}
+
+	return links
+}
+
+// rolloutProvider returns the scm provider, repository and sha of the commit being rolled out, if they are known
+func (b *deploymentInformer) rolloutProvider(deployment *appsv1.Deployment) (scm.Provider, string, string, bool) {
+	repo, sha := b.gitRevision(deployment)
+	if repo == "" || sha == "" {
+		return nil, "", "", false
+	}
+
+	provider, err := b.SCMResolver.Provider(repo, deployment.GetAnnotations()[hermodSCMAnnotation])
+	if err != nil {
+		log.Warnf("failed to select scm provider for deployment `%s` in `%s` namespace: %v", deployment.Name, deployment.Namespace, err)
+		return nil, "", "", false
+	}
+
+	return provider, repo, sha, true
+}
+
+// rolloutEvent describes a phase of the rollout of a deployment for message templates
+func (b *deploymentInformer) rolloutEvent(phase string, deployment *appsv1.Deployment, errorList []rolloutError) templates.Event {
+	var replicas int32 = 1
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+
+	event := templates.Event{
+		Phase:         phase,
+		Cluster:       getClusterName(),
+		Namespace:     deployment.Namespace,
+		Name:          deployment.Name,
+		Revision:      deployment.Annotations[revision],
+		Labels:        deployment.Labels,
+		Annotations:   deployment.Annotations,
+		Replicas:      replicas,
+		ReadyReplicas: deployment.Status.ReadyReplicas,
+	}
+
+	for _, container := range deployment.Spec.Template.Spec.Containers {
+		event.Images = append(event.Images, container.Image)
+	}
+
+	if phase != templates.PhaseStarted {
+		if duration, ok := rolloutDuration(deployment, time.Now()); ok {
+			event.Duration = duration.String()
+		}
+	}
+
+	for _, e := range errorList {
+		event.Errors = append(event.Errors, templates.Error{Reason: e.reason, Message: e.message})
+	}
+
+	event.Git.Repo, event.Git.SHA = b.gitRevision(deployment)
+	if provider, repo, sha, ok := b.rolloutProvider(deployment); ok {
+		event.Git.CommitURL = provider.CommitURL(repo, sha)
+		event.Git.PullRequestURL = provider.MergeRequestSearchURL(repo, sha)
+	}
+
+	if helm := b.helmRelease(deployment); helm != nil {
+		event.Helm = helm.event()
+	}
+
+	return event
+}
+
+// render renders the message template of the sink for the event, falling back to the default message
+func (b *deploymentInformer) render(sink string, event templates.Event, fallback string) string {
+	text, ok, err := b.templates().Render(sink, event)
+	return rendered(sink, event, text, ok, err, fallback)
+}
+
+// renderHeader renders the header template of the sink for the event, falling back to the default header
+func (b *deploymentInformer) renderHeader(sink string, event templates.Event, fallback string) string {
+	text, ok, err := b.templates().RenderHeader(sink, event)
+	return rendered(sink, event, text, ok, err, fallback)
+}
+
+// rendered returns the rendered text, reporting template errors and falling back to the default text
+func rendered(sink string, event templates.Event, text string, ok bool, err error, fallback string) string {
+	if err != nil {
+		message := fmt.Sprintf("failed to render %s message for deployment `%s` in `%s` namespace: %v", sink, event.Name, event.Namespace, err)
+		log.Error(message)
+		sentry.CaptureMessage(message)
+		return fallback
+	}
+	if !ok {
+		return fallback
+	}
+
+	return text
+}
+
+// slackErrors lists the errors of a rollout for a Slack message
+func slackErrors(errorList []rolloutError) []slack.Error {
+	var errors []slack.Error
+	for _, e := range errorList {
+		errors = append(errors, slack.Error{Reason: e.reason, Message: e.message})
+	}
+
+	return errors
+}
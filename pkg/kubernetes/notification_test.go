@@ -0,0 +1,51 @@
+package kubernetes
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/uswitch/hermod/pkg/slack"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRolloutFields(t *testing.T) {
+	var replicas int32 = 3
+	now := time.Date(2021, 6, 1, 12, 1, 30, 0, time.UTC)
+	deployment := func(started string) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{hermodRolloutStartedAnnotation: started}},
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+			Status:     appsv1.DeploymentStatus{ReadyReplicas: 2},
+		}
+	}
+	tests := []struct {
+		name           string
+		deployment     *appsv1.Deployment
+		expectedOutput []slack.Field
+	}{
+		{
+			name:       "with duration",
+			deployment: deployment("2021-06-01T12:00:00Z"),
+			expectedOutput: []slack.Field{
+				{Name: "Replicas", Value: "`2/3` ready"},
+				{Name: "Duration", Value: "1m30s"},
+			},
+		},
+		{
+			name:       "start not recorded",
+			deployment: deployment(""),
+			expectedOutput: []slack.Field{
+				{Name: "Replicas", Value: "`2/3` ready"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if output := rolloutFields(tt.deployment, now); !reflect.DeepEqual(output, tt.expectedOutput) {
+				t.Errorf("rolloutFields() = %v, expectedOutput %v", output, tt.expectedOutput)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package kubernetes
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/uswitch/hermod/pkg/slack"
+	"github.com/uswitch/hermod/pkg/templates"
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+const (
+	hermodOwnersAnnotation           = "hermod.uswitch.com/owners"
+	hermodEscalationOwnersAnnotation = "hermod.uswitch.com/escalation-owners"
+	hermodEscalateAtAnnotation       = "hermod.uswitch.com/escalate-at"
+)
+
+// ownerMentions will resolve the owners listed in the annotation of the deployment or its namespace into Slack mentions
+func (b *deploymentInformer) ownerMentions(deployment *appsv1.Deployment, annotation string) []string {
+	value, err := b.getSetting(deployment, annotation)
+	if err != nil {
+		log.Errorf("failed to get owners of deployment: %s", err)
+		return nil
+	}
+
+	owners := splitList(value)
+	if len(owners) == 0 {
+		return nil
+	}
+
+	mentions, err := b.SlackClient.Mentions(owners)
+	if err != nil {
+		log.Warnf("cannot mention all owners of deployment `%s` in `%s` namespace: %v", deployment.Name, deployment.Namespace, err)
+	}
+
+	return mentions
+}
+
+// escalationAnnotations will schedule the escalation of a failed rollout, when the deployment or its namespace has escalation owners
+func (b *deploymentInformer) escalationAnnotations(deployment *appsv1.Deployment, now time.Time) map[string]string {
+	if b.EscalationDelay <= 0 {
+		return nil
+	}
+
+	owners, err := b.getSetting(deployment, hermodEscalationOwnersAnnotation)
+	if err != nil || len(splitList(owners)) == 0 {
+		return nil
+	}
+
+	return map[string]string{hermodEscalateAtAnnotation: now.Add(b.EscalationDelay).UTC().Format(time.RFC3339)}
+}
+
+// escalate will mention the escalation owners once a failed rollout is still failing at its escalation time
+func (b *deploymentInformer) escalate(deployment *appsv1.Deployment, now time.Time) {
+	if deployment.Annotations[hermodStateAnnotation] != hermodFailState || b.namespaceIndexer == nil {
+		return
+	}
+
+	escalateAt, err := time.Parse(time.RFC3339, deployment.Annotations[hermodEscalateAtAnnotation])
+	if err != nil || now.Before(escalateAt) {
+		return
+	}
+
+	// the alert level may have been lowered since the rollout failed, escalations are only sent when failures are
+	alertLevel, err := b.alertLevel(deployment)
+	if err != nil || !b.shouldAlert(alertLevel, templates.PhaseFailed, deployment) {
+		return
+	}
+
+	routes, err := b.getSlackRoutes(deployment)
+	if err != nil || !routes.tracked() {
+		return
+	}
+
+	// clear the escalation time first so the escalation is only sent once
+	err = addAnnotations(b.Context, b.client, deployment.Namespace, deployment.DeepCopy(), map[string]string{hermodEscalateAtAnnotation: ""})
+	if err != nil {
+		log.Errorf("failed to add annotation: %v", err)
+		return
+	}
+
+	mentions := b.ownerMentions(deployment, hermodEscalationOwnersAnnotation)
+	if len(mentions) == 0 {
+		return
+	}
+
+	msg := fmt.Sprintf("*Deployment `%s` in `%s` namespace on `%s` cluster is still failing after %s.*", deployment.Name, deployment.Namespace, getClusterName(), b.EscalationDelay)
+	log.Info(msg)
+
+	event := b.rolloutEvent(templates.PhaseEscalated, deployment, nil)
+	event.EscalatedAfter = b.EscalationDelay.String()
+
+	b.sendSlackMessage(routes, templates.PhaseFailed, slack.Message{
+		Header:   b.renderHeader(templates.SinkSlack, event, fmt.Sprintf("Still failing: %s", deployment.Name)),
+		Summary:  b.render(templates.SinkSlack, event, msg),
+		Context:  rolloutContext(deployment),
+		Mentions: mentions,
+		Color:    slack.RedColor,
+	})
+}
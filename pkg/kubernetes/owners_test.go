@@ -0,0 +1,98 @@
+package kubernetes
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestEscalationAnnotations(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	indexer := newNamespaceIndexer(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "escalating", Annotations: map[string]string{hermodEscalationOwnersAnnotation: "S012AB3CD"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "quiet"}},
+	)
+
+	tests := []struct {
+		name           string
+		namespace      string
+		delay          time.Duration
+		expectedOutput map[string]string
+	}{
+		{
+			name:           "namespace with escalation owners",
+			namespace:      "escalating",
+			delay:          30 * time.Minute,
+			expectedOutput: map[string]string{hermodEscalateAtAnnotation: "2021-06-01T12:30:00Z"},
+		},
+		{
+			name:      "namespace without escalation owners",
+			namespace: "quiet",
+			delay:     30 * time.Minute,
+		},
+		{
+			name:      "escalation disabled",
+			namespace: "escalating",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &deploymentInformer{namespaceIndexer: indexer, Settings: Settings{EscalationDelay: tt.delay}}
+			deployment := testDeployment(tt.namespace, nil, nil)
+			if output := b.escalationAnnotations(deployment, now); !reflect.DeepEqual(output, tt.expectedOutput) {
+				t.Errorf("escalationAnnotations() = %v, expectedOutput %v", output, tt.expectedOutput)
+			}
+		})
+	}
+}
+
+func TestEscalate(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 30, 0, 0, time.UTC)
+	indexer := newNamespaceIndexer(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "failures", Annotations: map[string]string{hermodSlackChannelAnnotation: "team", hermodAlertAnnotation: "failure"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "silent", Annotations: map[string]string{hermodSlackChannelAnnotation: "team", hermodAlertAnnotation: "none"}}},
+	)
+
+	tests := []struct {
+		name           string
+		namespace      string
+		expectedOutput bool
+	}{
+		{
+			name:           "failures alerted",
+			namespace:      "failures",
+			expectedOutput: true,
+		},
+		{
+			name:      "alert level none",
+			namespace: "silent",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: tt.namespace, Annotations: map[string]string{
+				hermodStateAnnotation:      hermodFailState,
+				hermodEscalateAtAnnotation: "2021-06-01T12:00:00Z",
+			}}}
+			client := k8sfake.NewSimpleClientset(deployment)
+			b := &deploymentInformer{Context: context.Background(), client: client, namespaceIndexer: indexer, Settings: Settings{EscalationDelay: 30 * time.Minute}}
+
+			// without escalation owners nobody is mentioned, the escalation is still cleared once due
+			b.escalate(deployment, now)
+
+			escalated := false
+			for _, action := range client.Actions() {
+				escalated = escalated || action.GetVerb() == "patch"
+			}
+			if escalated != tt.expectedOutput {
+				t.Errorf("escalate() escalated = %v, expectedOutput %v", escalated, tt.expectedOutput)
+			}
+		})
+	}
+}
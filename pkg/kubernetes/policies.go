@@ -0,0 +1,559 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	log "github.com/sirupsen/logrus"
+	"github.com/uswitch/hermod/pkg/github"
+	"github.com/uswitch/hermod/pkg/templates"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	readyCondition = "Ready"
+	validReason    = "Valid"
+	invalidReason  = "Invalid"
+
+	// how long deployments wait for the custom resources to be listed, before they are tracked without them
+	policySyncTimeout = 30 * time.Second
+)
+
+var (
+	hermodConfigs        = schema.GroupVersionResource{Group: "hermod.uswitch.com", Version: "v1alpha1", Resource: "hermodconfigs"}
+	notificationPolicies = schema.GroupVersionResource{Group: "hermod.uswitch.com", Version: "v1alpha1", Resource: "notificationpolicies"}
+)
+
+// PolicyOptions configures the HermodConfig and NotificationPolicy custom resources Hermod is configured by
+type PolicyOptions struct {
+	// Watch enables watching the custom resources, their definitions must be installed
+	Watch bool
+	// ConfigName is the name of the HermodConfig to apply, others are ignored
+	ConfigName string
+}
+
+// slackSinkSpec is where Slack messages are sent
+type slackSinkSpec struct {
+	// DefaultChannel replaces --default-slack-channel, only in a HermodConfig
+	DefaultChannel string              `json:"defaultChannel,omitempty"`
+	Channels       []string            `json:"channels,omitempty"`
+	Routes         map[string][]string `json:"routes,omitempty"`
+}
+
+// githubSinkSpec is how rollouts are reported to GitHub, each value set replaces the --github-* flag of the same name
+type githubSinkSpec struct {
+	Deployments   *bool  `json:"deployments,omitempty"`
+	CommitStatus  *bool  `json:"commitStatus,omitempty"`
+	CheckRuns     *bool  `json:"checkRuns,omitempty"`
+	CommitDetails *bool  `json:"commitDetails,omitempty"`
+	StatusContext string `json:"statusContext,omitempty"`
+}
+
+// reports tells whether rollouts are reported to GitHub at all with the sink
+func (s githubSinkSpec) reports() bool {
+	for _, enabled := range []*bool{s.Deployments, s.CommitStatus, s.CheckRuns, s.CommitDetails} {
+		if enabled != nil && *enabled {
+			return true
+		}
+	}
+	return false
+}
+
+// hermodConfigSpec is the spec of the cluster-scoped HermodConfig, the defaults of every deployment
+type hermodConfigSpec struct {
+	Slack              slackSinkSpec     `json:"slack,omitempty"`
+	Github             githubSinkSpec    `json:"github,omitempty"`
+	AlertLevel         string            `json:"alertLevel,omitempty"`
+	MaintenanceWindows []string          `json:"maintenanceWindows,omitempty"`
+	Templates          map[string]string `json:"templates,omitempty"`
+}
+
+// notificationPolicySpec is the spec of a NotificationPolicy, the settings of the deployments it selects in its namespace.
+// Each setting stands in for the annotation of the same name, which still takes precedence.
+type notificationPolicySpec struct {
+	Selector          *metav1.LabelSelector `json:"selector,omitempty"`
+	Slack             slackSinkSpec         `json:"slack,omitempty"`
+	AlertLevel        string                `json:"alertLevel,omitempty"`
+	Owners            []string              `json:"owners,omitempty"`
+	EscalationOwners  []string              `json:"escalationOwners,omitempty"`
+	MaintenanceWindow string                `json:"maintenanceWindow,omitempty"`
+	AutoRollback      *bool                 `json:"autoRollback,omitempty"`
+}
+
+type policyStatus struct {
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// clusterConfig is a validated HermodConfig
+type clusterConfig struct {
+	defaultChannel     string
+	github             githubSinkSpec
+	maintenanceWindows []MaintenanceWindow
+	templates          *templates.Templates
+	settings           map[string]string
+}
+
+// notificationPolicy is a validated NotificationPolicy
+type notificationPolicy struct {
+	name     string
+	selector labels.Selector
+	settings map[string]string
+}
+
+// policies keeps the HermodConfig and NotificationPolicies applied, they are updated while deployments are handled
+type policies struct {
+	sync.RWMutex
+	cluster    *clusterConfig
+	namespaces map[string][]notificationPolicy
+}
+
+// parseHermodConfig will validate a HermodConfig
+func parseHermodConfig(spec hermodConfigSpec) (*clusterConfig, error) {
+	if len(spec.Slack.Channels) > 0 {
+		return nil, fmt.Errorf("slack.channels cannot be set cluster-wide, use slack.defaultChannel")
+	}
+
+	settings, err := slackSettings(spec.Slack, spec.AlertLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &clusterConfig{defaultChannel: spec.Slack.DefaultChannel, github: spec.Github, settings: settings}
+	for _, value := range spec.MaintenanceWindows {
+		window, err := ParseMaintenanceWindow(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid maintenance window: %v", err)
+		}
+		config.maintenanceWindows = append(config.maintenanceWindows, window)
+	}
+
+	if len(spec.Templates) > 0 {
+		config.templates, err = templates.Parse(spec.Templates)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return config, nil
+}
+
+// parseNotificationPolicy will validate a NotificationPolicy
+func parseNotificationPolicy(name string, spec notificationPolicySpec) (notificationPolicy, error) {
+	if spec.Slack.DefaultChannel != "" {
+		return notificationPolicy{}, fmt.Errorf("slack.defaultChannel can only be set in a HermodConfig, use slack.channels")
+	}
+
+	selector := labels.Everything()
+	if spec.Selector != nil {
+		var err error
+		selector, err = metav1.LabelSelectorAsSelector(spec.Selector)
+		if err != nil {
+			return notificationPolicy{}, fmt.Errorf("invalid selector: %v", err)
+		}
+	}
+
+	settings, err := slackSettings(spec.Slack, spec.AlertLevel)
+	if err != nil {
+		return notificationPolicy{}, err
+	}
+
+	if len(spec.Owners) > 0 {
+		settings[hermodOwnersAnnotation] = strings.Join(spec.Owners, ",")
+	}
+	if len(spec.EscalationOwners) > 0 {
+		settings[hermodEscalationOwnersAnnotation] = strings.Join(spec.EscalationOwners, ",")
+	}
+	if spec.MaintenanceWindow != "" {
+		if _, err := ParseMaintenanceWindow(spec.MaintenanceWindow); err != nil {
+			return notificationPolicy{}, fmt.Errorf("invalid maintenance window: %v", err)
+		}
+		settings[hermodMaintenanceWindowAnnotation] = spec.MaintenanceWindow
+	}
+	if spec.AutoRollback != nil {
+		settings[hermodAutoRollbackAnnotation] = strconv.FormatBool(*spec.AutoRollback)
+	}
+
+	return notificationPolicy{name: name, selector: selector, settings: settings}, nil
+}
+
+// slackSettings will validate the Slack sink and alert level, returning them as the annotations they stand in for
+func slackSettings(slack slackSinkSpec, alertLevel string) (map[string]string, error) {
+	settings := map[string]string{}
+
+	if len(slack.Channels) > 0 {
+		settings[hermodSlackChannelAnnotation] = strings.Join(slack.Channels, ",")
+	}
+
+	if len(slack.Routes) > 0 {
+		routes, err := json.Marshal(slack.Routes)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := parseSlackRoutes("", string(routes)); err != nil {
+			return nil, err
+		}
+		settings[hermodSlackRoutesAnnotation] = string(routes)
+	}
+
+	if alertLevel != "" {
+		if _, ok := alertOutcomes[alertLevel]; !ok {
+			return nil, fmt.Errorf("unknown alert level %q", alertLevel)
+		}
+		settings[hermodAlertAnnotation] = alertLevel
+	}
+
+	return settings, nil
+}
+
+// setting will return the value the NotificationPolicies of the namespace of the deployment, or else the HermodConfig,
+// give the annotation. Policies apply in the order of their names, the first setting a value wins.
+func (p *policies) setting(deployment *appsv1.Deployment, annotation string) string {
+	p.RLock()
+	defer p.RUnlock()
+
+	for _, policy := range p.namespaces[deployment.Namespace] {
+		if value, ok := policy.settings[annotation]; ok && policy.selector.Matches(labels.Set(deployment.Labels)) {
+			return value
+		}
+	}
+
+	if p.cluster != nil {
+		return p.cluster.settings[annotation]
+	}
+
+	return ""
+}
+
+func (p *policies) setCluster(config *clusterConfig) {
+	p.Lock()
+	defer p.Unlock()
+
+	p.cluster = config
+}
+
+func (p *policies) setPolicy(namespace string, policy notificationPolicy) {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.namespaces == nil {
+		p.namespaces = map[string][]notificationPolicy{}
+	}
+
+	var namespacePolicies []notificationPolicy
+	for _, existing := range p.namespaces[namespace] {
+		if existing.name != policy.name {
+			namespacePolicies = append(namespacePolicies, existing)
+		}
+	}
+	namespacePolicies = append(namespacePolicies, policy)
+	sort.Slice(namespacePolicies, func(i, j int) bool { return namespacePolicies[i].name < namespacePolicies[j].name })
+
+	p.namespaces[namespace] = namespacePolicies
+}
+
+// replace will apply the defaults and policies of every namespace at once
+func (p *policies) replace(cluster *clusterConfig, namespaces map[string][]notificationPolicy) {
+	p.Lock()
+	defer p.Unlock()
+
+	p.cluster, p.namespaces = cluster, namespaces
+}
+
+func (p *policies) deletePolicy(namespace, name string) {
+	p.Lock()
+	defer p.Unlock()
+
+	var namespacePolicies []notificationPolicy
+	for _, existing := range p.namespaces[namespace] {
+		if existing.name != name {
+			namespacePolicies = append(namespacePolicies, existing)
+		}
+	}
+	p.namespaces[namespace] = namespacePolicies
+}
+
+// defaultChannel will return the default channel of the HermodConfig, if it sets one
+func (p *policies) defaultChannel() string {
+	p.RLock()
+	defer p.RUnlock()
+
+	if p.cluster == nil {
+		return ""
+	}
+	return p.cluster.defaultChannel
+}
+
+// githubSink will return how the HermodConfig reports rollouts to GitHub
+func (p *policies) githubSink() githubSinkSpec {
+	p.RLock()
+	defer p.RUnlock()
+
+	if p.cluster == nil {
+		return githubSinkSpec{}
+	}
+	return p.cluster.github
+}
+
+// maintenanceWindows will return the maintenance windows of the HermodConfig
+func (p *policies) maintenanceWindows() []MaintenanceWindow {
+	p.RLock()
+	defer p.RUnlock()
+
+	if p.cluster == nil {
+		return nil
+	}
+	return p.cluster.maintenanceWindows
+}
+
+// templates will return the templates of the HermodConfig, if it has any
+func (p *policies) templates() *templates.Templates {
+	p.RLock()
+	defer p.RUnlock()
+
+	if p.cluster == nil {
+		return nil
+	}
+	return p.cluster.templates
+}
+
+// getSetting will return the annotation of the deployment or its namespace, falling back to the value
+// set by a NotificationPolicy or the HermodConfig
+func (b *deploymentInformer) getSetting(deployment *appsv1.Deployment, annotation string) (string, error) {
+	value, err := getDeploymentOrNamespaceAnnotation(deployment, b.namespaceIndexer, annotation)
+	if err != nil || value != "" {
+		return value, err
+	}
+
+	return b.policies.setting(deployment, annotation), nil
+}
+
+// defaultSlackChannel is the default channel of the HermodConfig, or else of --default-slack-channel
+func (b *deploymentInformer) defaultSlackChannel() string {
+	if channel := b.policies.defaultChannel(); channel != "" {
+		return channel
+	}
+	return b.DefaultSlackChannel
+}
+
+// githubOptions are the --github-* flags, overridden by the GitHub sink of the HermodConfig
+func (b *deploymentInformer) githubOptions() GithubOptions {
+	options := b.GithubOptions
+	sink := b.policies.githubSink()
+	if sink.Deployments != nil {
+		options.Deployments = *sink.Deployments
+	}
+	if sink.CommitStatus != nil {
+		options.CommitStatus = *sink.CommitStatus
+	}
+	if sink.CheckRuns != nil {
+		options.CheckRuns = *sink.CheckRuns
+	}
+	if sink.CommitDetails != nil {
+		options.CommitDetails = *sink.CommitDetails
+	}
+	if sink.StatusContext != "" {
+		options.StatusContext = sink.StatusContext
+	}
+
+	return options
+}
+
+// checkGithubSink will tell whether rollouts can be reported to GitHub as configured, which needs a GitHub client
+func checkGithubSink(config *clusterConfig, client *github.Client) error {
+	if config != nil && config.github.reports() && client == nil {
+		return fmt.Errorf("github cannot be enabled without the GITHUB_TOKEN environment variable")
+	}
+	return nil
+}
+
+// templates are the templates of the HermodConfig, or else those loaded at startup
+func (b *deploymentInformer) templates() *templates.Templates {
+	if t := b.policies.templates(); t != nil {
+		return t
+	}
+	return b.Templates
+}
+
+// watchPolicies will apply the HermodConfig and NotificationPolicies as they change, reporting whether they are valid in their status
+func (b *deploymentInformer) watchPolicies(ctx context.Context) {
+	factory := dynamicinformer.NewDynamicSharedInformerFactory(b.DynamicClient, 0)
+
+	configs := factory.ForResource(hermodConfigs).Informer()
+	configs.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    b.applyHermodConfig,
+		UpdateFunc: func(_, obj interface{}) { b.applyHermodConfig(obj) },
+		DeleteFunc: func(obj interface{}) {
+			if _, name := deletedKey(obj); name == b.Policies.ConfigName {
+				log.Infof("HermodConfig %s deleted", name)
+				b.policies.setCluster(nil)
+			}
+		},
+	})
+
+	namespacePolicies := factory.ForResource(notificationPolicies).Informer()
+	namespacePolicies.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    b.applyNotificationPolicy,
+		UpdateFunc: func(_, obj interface{}) { b.applyNotificationPolicy(obj) },
+		DeleteFunc: func(obj interface{}) {
+			namespace, name := deletedKey(obj)
+			log.Infof("NotificationPolicy %s/%s deleted", namespace, name)
+			b.policies.deletePolicy(namespace, name)
+		},
+	})
+
+	factory.Start(ctx.Done())
+
+	// missing definitions or permissions must not stop deployments from being tracked, the informers keep retrying
+	syncCtx, cancel := context.WithTimeout(ctx, policySyncTimeout)
+	defer cancel()
+
+	synced := true
+	for resource, ok := range factory.WaitForCacheSync(syncCtx.Done()) {
+		if ok {
+			continue
+		}
+		synced = false
+
+		reason := "check the custom resource definitions are installed and Hermod may list and watch them"
+		if _, err := b.DynamicClient.Resource(resource).List(ctx, metav1.ListOptions{Limit: 1}); err != nil {
+			reason = err.Error()
+		}
+		message := fmt.Sprintf("failed to list %s within %s, deployments are tracked without them until they are listed: %s", resource.Resource, policySyncTimeout, reason)
+		log.Error(message)
+		sentry.CaptureMessage(message)
+	}
+
+	if synced {
+		log.Info("policy cache controller synced")
+	}
+}
+
+func (b *deploymentInformer) applyHermodConfig(obj interface{}) {
+	resource, ok := obj.(*unstructured.Unstructured)
+	if !ok || resource.GetName() != b.Policies.ConfigName {
+		return
+	}
+
+	var spec hermodConfigSpec
+	err := fromUnstructuredSpec(resource, &spec)
+	var config *clusterConfig
+	if err == nil {
+		config, err = parseHermodConfig(spec)
+	}
+	if err == nil {
+		err = checkGithubSink(config, b.currentSettings().GithubClient)
+	}
+
+	if err != nil {
+		log.Warnf("invalid HermodConfig %s, keeping the previous configuration: %v", resource.GetName(), err)
+	} else {
+		log.Infof("applied HermodConfig %s", resource.GetName())
+		b.policies.setCluster(config)
+	}
+
+	b.setReadyCondition(hermodConfigs, resource, err)
+}
+
+func (b *deploymentInformer) applyNotificationPolicy(obj interface{}) {
+	resource, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+
+	var spec notificationPolicySpec
+	err := fromUnstructuredSpec(resource, &spec)
+	var policy notificationPolicy
+	if err == nil {
+		policy, err = parseNotificationPolicy(resource.GetName(), spec)
+	}
+
+	if err != nil {
+		log.Warnf("invalid NotificationPolicy %s/%s, keeping the previous policy: %v", resource.GetNamespace(), resource.GetName(), err)
+	} else {
+		log.Infof("applied NotificationPolicy %s/%s", resource.GetNamespace(), resource.GetName())
+		b.policies.setPolicy(resource.GetNamespace(), policy)
+	}
+
+	b.setReadyCondition(notificationPolicies, resource, err)
+}
+
+// setReadyCondition will record in the Ready condition of the resource whether it is valid, unless it already is
+func (b *deploymentInformer) setReadyCondition(gvr schema.GroupVersionResource, resource *unstructured.Unstructured, validationErr error) {
+	condition := metav1.Condition{
+		Type:               readyCondition,
+		Status:             metav1.ConditionTrue,
+		Reason:             validReason,
+		Message:            "applied",
+		ObservedGeneration: resource.GetGeneration(),
+	}
+	if validationErr != nil {
+		condition.Status, condition.Reason, condition.Message = metav1.ConditionFalse, invalidReason, validationErr.Error()
+	}
+
+	var status policyStatus
+	if raw, ok := resource.Object["status"].(map[string]interface{}); ok {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, &status); err != nil {
+			log.Warnf("failed to read status of %s %s: %v", resource.GetKind(), resource.GetName(), err)
+		}
+	}
+
+	existing := meta.FindStatusCondition(status.Conditions, readyCondition)
+	if existing != nil && existing.Status == condition.Status && existing.Reason == condition.Reason &&
+		existing.Message == condition.Message && existing.ObservedGeneration == condition.ObservedGeneration {
+		return
+	}
+	meta.SetStatusCondition(&status.Conditions, condition)
+
+	raw, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&status)
+	if err != nil {
+		log.Errorf("failed to convert status of %s %s: %v", resource.GetKind(), resource.GetName(), err)
+		return
+	}
+
+	updated := resource.DeepCopy()
+	updated.Object["status"] = raw
+	if _, err := b.DynamicClient.Resource(gvr).Namespace(resource.GetNamespace()).UpdateStatus(b.Context, updated, metav1.UpdateOptions{}); err != nil {
+		message := fmt.Sprintf("failed to update status of %s %s: %v", resource.GetKind(), resource.GetName(), err)
+		log.Error(message)
+		sentry.CaptureMessage(message)
+	}
+}
+
+// fromUnstructuredSpec will decode the spec of a custom resource
+func fromUnstructuredSpec(resource *unstructured.Unstructured, spec interface{}) error {
+	raw, ok := resource.Object["spec"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, spec); err != nil {
+		return fmt.Errorf("invalid spec: %v", err)
+	}
+	return nil
+}
+
+// deletedKey will return the namespace and name of a deleted resource
+func deletedKey(obj interface{}) (string, string) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return "", ""
+	}
+
+	namespace, name, _ := cache.SplitMetaNamespaceKey(key)
+	return namespace, name
+}
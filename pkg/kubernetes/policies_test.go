@@ -0,0 +1,221 @@
+package kubernetes
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/uswitch/hermod/pkg/github"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func TestParseNotificationPolicy(t *testing.T) {
+	enabled := true
+
+	tests := []struct {
+		name           string
+		spec           notificationPolicySpec
+		expectedOutput map[string]string
+		expectError    bool
+	}{
+		{
+			name: "settings as annotations",
+			spec: notificationPolicySpec{
+				Slack:        slackSinkSpec{Channels: []string{"payments-deploys", "payments"}, Routes: map[string][]string{"failed": {"payments-alerts"}}},
+				AlertLevel:   "failure",
+				Owners:       []string{"U012AB3CD"},
+				AutoRollback: &enabled,
+			},
+			expectedOutput: map[string]string{
+				hermodSlackChannelAnnotation: "payments-deploys,payments",
+				hermodSlackRoutesAnnotation:  `{"failed":["payments-alerts"]}`,
+				hermodAlertAnnotation:        "failure",
+				hermodOwnersAnnotation:       "U012AB3CD",
+				hermodAutoRollbackAnnotation: "true",
+			},
+		},
+		{
+			name:        "unknown alert level",
+			spec:        notificationPolicySpec{AlertLevel: "sometimes"},
+			expectError: true,
+		},
+		{
+			name:        "unknown outcome",
+			spec:        notificationPolicySpec{Slack: slackSinkSpec{Routes: map[string][]string{"paused": {"payments"}}}},
+			expectError: true,
+		},
+		{
+			name:        "invalid maintenance window",
+			spec:        notificationPolicySpec{MaintenanceWindow: "saturday"},
+			expectError: true,
+		},
+		{
+			name:        "default channel",
+			spec:        notificationPolicySpec{Slack: slackSinkSpec{DefaultChannel: "deploys"}},
+			expectError: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := parseNotificationPolicy("payments", tt.spec)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("parseNotificationPolicy() error = %v, expectError %v", err, tt.expectError)
+			}
+			if err == nil && !reflect.DeepEqual(policy.settings, tt.expectedOutput) {
+				t.Errorf("parseNotificationPolicy() = %v, expectedOutput %v", policy.settings, tt.expectedOutput)
+			}
+		})
+	}
+}
+
+func TestGetSetting(t *testing.T) {
+	indexer := newNamespaceIndexer(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "payments"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "search", Annotations: map[string]string{hermodAlertAnnotation: "result"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "other"}},
+	)
+
+	b := &deploymentInformer{namespaceIndexer: indexer}
+	b.policies.setCluster(&clusterConfig{settings: map[string]string{hermodAlertAnnotation: "all"}})
+	b.policies.setPolicy("payments", notificationPolicy{name: "b-everything", selector: labels.Everything(), settings: map[string]string{hermodAlertAnnotation: "none"}})
+	b.policies.setPolicy("payments", notificationPolicy{name: "a-workers", selector: labels.SelectorFromSet(labels.Set{"tier": "worker"}), settings: map[string]string{hermodAlertAnnotation: "failure"}})
+
+	tests := []struct {
+		name           string
+		namespace      string
+		labels         map[string]string
+		annotations    map[string]string
+		expectedOutput string
+	}{
+		{
+			name:           "deployment annotation",
+			namespace:      "payments",
+			annotations:    map[string]string{hermodAlertAnnotation: "changes-only"},
+			expectedOutput: "changes-only",
+		},
+		{
+			name:           "namespace annotation",
+			namespace:      "search",
+			expectedOutput: "result",
+		},
+		{
+			name:           "first matching policy",
+			namespace:      "payments",
+			labels:         map[string]string{"tier": "worker"},
+			expectedOutput: "failure",
+		},
+		{
+			name:           "policy selecting every deployment",
+			namespace:      "payments",
+			expectedOutput: "none",
+		},
+		{
+			name:           "hermod config",
+			namespace:      "other",
+			expectedOutput: "all",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deployment := testDeployment(tt.namespace, tt.annotations, tt.labels)
+			output, err := b.getSetting(deployment, hermodAlertAnnotation)
+			if err != nil {
+				t.Fatalf("getSetting() error = %v", err)
+			}
+			if output != tt.expectedOutput {
+				t.Errorf("getSetting() = %v, expectedOutput %v", output, tt.expectedOutput)
+			}
+		})
+	}
+}
+
+func TestGithubOptions(t *testing.T) {
+	enabled, disabled := true, false
+	flags := GithubOptions{Deployments: true, CommitDetails: true, StatusContext: "hermod"}
+
+	tests := []struct {
+		name           string
+		spec           githubSinkSpec
+		client         *github.Client
+		expectedOutput GithubOptions
+		expectError    bool
+	}{
+		{
+			name:           "no github sink",
+			expectedOutput: flags,
+		},
+		{
+			name:           "github sink replacing flags",
+			spec:           githubSinkSpec{Deployments: &disabled, CheckRuns: &enabled, StatusContext: "rollouts"},
+			client:         &github.Client{},
+			expectedOutput: GithubOptions{CheckRuns: true, CommitDetails: true, StatusContext: "rollouts"},
+		},
+		{
+			name:        "github sink without a token",
+			spec:        githubSinkSpec{CommitStatus: &enabled},
+			expectError: true,
+		},
+		{
+			name:           "github sink disabling reporting without a token",
+			spec:           githubSinkSpec{Deployments: &disabled, CommitDetails: &disabled},
+			expectedOutput: GithubOptions{StatusContext: "hermod"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &deploymentInformer{Settings: Settings{GithubClient: tt.client, GithubOptions: flags}}
+			config, err := parseHermodConfig(hermodConfigSpec{Github: tt.spec})
+			if err == nil {
+				err = checkGithubSink(config, b.GithubClient)
+			}
+			if (err != nil) != tt.expectError {
+				t.Fatalf("checkGithubSink() error = %v, expectError %v", err, tt.expectError)
+			}
+			if err != nil {
+				return
+			}
+
+			b.policies.setCluster(config)
+			if output := b.githubOptions(); output != tt.expectedOutput {
+				t.Errorf("githubOptions() = %v, expectedOutput %v", output, tt.expectedOutput)
+			}
+		})
+	}
+}
+
+func TestApplyNotificationPolicy(t *testing.T) {
+	policy := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "hermod.uswitch.com/v1alpha1",
+		"kind":       "NotificationPolicy",
+		"metadata":   map[string]interface{}{"name": "payments", "namespace": "payments", "generation": int64(2)},
+		"spec":       map[string]interface{}{"alertLevel": "sometimes"},
+	}}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		notificationPolicies: "NotificationPolicyList",
+	}, policy)
+	b := &deploymentInformer{Context: context.Background(), DynamicClient: client}
+
+	b.applyNotificationPolicy(policy)
+
+	updated, err := client.Resource(notificationPolicies).Namespace("payments").Get(context.Background(), "payments", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	conditions, _, _ := unstructured.NestedSlice(updated.Object, "status", "conditions")
+	if len(conditions) != 1 {
+		t.Fatalf("status conditions = %v, expected a Ready condition", conditions)
+	}
+	condition := conditions[0].(map[string]interface{})
+	if condition["status"] != "False" || condition["reason"] != invalidReason || condition["observedGeneration"] != int64(2) {
+		t.Errorf("Ready condition = %v, expected an invalid policy at generation 2", condition)
+	}
+	if output := b.policies.setting(testDeployment("payments", nil, nil), hermodAlertAnnotation); output != "" {
+		t.Errorf("setting() = %v, expected the invalid policy not to apply", output)
+	}
+}
@@ -0,0 +1,261 @@
+package kubernetes
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	log "github.com/sirupsen/logrus"
+	"github.com/uswitch/hermod/pkg/slack"
+	"github.com/uswitch/hermod/pkg/templates"
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+const (
+	hermodReleaseAnnotation = "hermod.uswitch.com/release"
+
+	helmInstanceLabel  = "app.kubernetes.io/instance"
+	helmManagedByLabel = "app.kubernetes.io/managed-by"
+	// labels of charts predating the recommended labels
+	helmReleaseLabel  = "release"
+	helmHeritageLabel = "heritage"
+)
+
+// ReleaseOptions configures grouping the rollouts of deployments released together into one message
+type ReleaseOptions struct {
+	// Group enables grouping deployments sharing a release key
+	Group bool
+	// Window is how long a release is tracked after its last rollout, later rollouts start a new release
+	Window time.Duration
+}
+
+// releaseKey will return the release the deployment was rolled out by, its hermod.uswitch.com/release annotation,
+// its Helm release or its git sha, in that order
+func (b *deploymentInformer) releaseKey(deployment *appsv1.Deployment) string {
+	if release := deployment.Annotations[hermodReleaseAnnotation]; release != "" {
+		return release
+	}
+
+	if release := helmReleaseName(deployment); release != "" {
+		return release
+	}
+
+	// the sha of deployments without git annotations can come from their Argo CD Application or Flux Kustomization
+	_, sha := b.gitRevision(deployment)
+	return sha
+}
+
+// releaseMembers will return the tracked deployments in the namespace of the deployment sharing its release key
+func (b *deploymentInformer) releaseMembers(deployment *appsv1.Deployment, key string) []*appsv1.Deployment {
+	var members []*appsv1.Deployment
+	for _, obj := range b.store.List() {
+		member, ok := obj.(*appsv1.Deployment)
+		if !ok || member.Namespace != deployment.Namespace || b.releaseKey(member) != key {
+			continue
+		}
+		if tracked, err := b.isTracked(member); err != nil || !tracked {
+			continue
+		}
+		members = append(members, member)
+	}
+
+	return members
+}
+
+type releaseRef struct {
+	namespace string
+	key       string
+}
+
+// release tracks the outcome of each deployment rolled out by a release and the messages reporting them
+type release struct {
+	ref         releaseRef
+	deployments []string
+	outcomes    map[string]string
+	lastEvent   time.Time
+	messages    map[string]slack.MessageRef
+	// helm is the latest revision of the Helm release, when the release is one
+	helm *helmRelease
+}
+
+type releases struct {
+	sync.Mutex
+	tracked map[releaseRef]*release
+}
+
+// get will return the release tracked for the ref, forgetting releases without rollouts for the window
+func (r *releases) get(ref releaseRef, window time.Duration, now time.Time) (*release, bool) {
+	r.Lock()
+	defer r.Unlock()
+
+	for key, tracked := range r.tracked {
+		if now.Sub(tracked.lastEvent) >= window {
+			delete(r.tracked, key)
+		}
+	}
+
+	tracked, ok := r.tracked[ref]
+	return tracked, ok
+}
+
+// start will track a new release for the ref, replacing the release tracked before
+func (r *releases) start(ref releaseRef) *release {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.tracked == nil {
+		r.tracked = map[releaseRef]*release{}
+	}
+
+	tracked := &release{ref: ref, outcomes: map[string]string{}, messages: map[string]slack.MessageRef{}}
+	r.tracked[ref] = tracked
+	return tracked
+}
+
+func (r *release) record(deployment, outcome string, now time.Time) {
+	if _, ok := r.outcomes[deployment]; !ok {
+		r.deployments = append(r.deployments, deployment)
+	}
+	r.outcomes[deployment] = outcome
+	r.lastEvent = now
+}
+
+// outcome of the release, failed once a deployment failed and succeeded only once every deployment succeeded
+func (r *release) outcome() string {
+	outcome := templates.PhaseSucceeded
+	for _, deployment := range r.deployments {
+		switch r.outcomes[deployment] {
+		case templates.PhaseFailed:
+			return templates.PhaseFailed
+		case templates.PhaseStarted:
+			outcome = templates.PhaseStarted
+		}
+	}
+
+	return outcome
+}
+
+// message will describe the rollout of every deployment of the release
+func (r *release) message() slack.Message {
+	var succeeded int
+	var lines []string
+	for _, deployment := range r.deployments {
+		outcome := r.outcomes[deployment]
+		if outcome == templates.PhaseSucceeded {
+			succeeded++
+		}
+		lines = append(lines, fmt.Sprintf("• `%s` %s", deployment, outcome))
+	}
+
+	message := slack.Message{
+		Context: []string{
+			fmt.Sprintf("*Cluster:* `%s`", getClusterName()),
+			fmt.Sprintf("*Namespace:* `%s`", r.ref.namespace),
+			fmt.Sprintf("*Release:* `%s`", r.ref.key),
+		},
+		Summary:  fmt.Sprintf("*%d of %d deployments of release `%s` in namespace `%s` rolled out on `%s` cluster.*", succeeded, len(r.deployments), r.ref.key, r.ref.namespace, getClusterName()),
+		Sections: []string{strings.Join(lines, "\n")},
+	}
+	if r.helm != nil {
+		message.Context = append(message.Context, r.helm.context())
+	}
+
+	switch r.outcome() {
+	case templates.PhaseFailed:
+		message.Header = fmt.Sprintf("Failed to roll out release %s", r.ref.key)
+		message.Color = slack.RedColor
+	case templates.PhaseSucceeded:
+		message.Header = fmt.Sprintf("Rolled out release %s", r.ref.key)
+		message.Color = slack.GreenColor
+	default:
+		message.Header = fmt.Sprintf("Rolling out release %s", r.ref.key)
+		message.Color = slack.OrangeColor
+	}
+
+	return message
+}
+
+// groupRelease will add the rollout to the message of its release when other deployments share its release key, and
+// tell whether it was grouped. Failures are added to the release but still sent on their own.
+func (b *deploymentInformer) groupRelease(routes slackRoutes, outcome string, deployment *appsv1.Deployment) bool {
+	if !b.Releases.Group {
+		return false
+	}
+
+	key := b.releaseKey(deployment)
+	if key == "" {
+		return false
+	}
+
+	now := time.Now()
+	ref := releaseRef{namespace: deployment.Namespace, key: key}
+	tracked, ok := b.releases.get(ref, b.Releases.Window, now)
+	if ok && outcome == templates.PhaseStarted && tracked.outcomes[deployment.Name] != "" && tracked.outcomes[deployment.Name] != templates.PhaseStarted {
+		// a deployment rolled out again, e.g. by the next upgrade of a Helm release, starts a new release
+		ok = false
+	}
+	if !ok {
+		// a failure alone does not start a release, nor does a deployment without others sharing its release key
+		if outcome == templates.PhaseFailed {
+			return false
+		}
+		members := b.releaseMembers(deployment, key)
+		if len(members) < 2 {
+			return false
+		}
+
+		tracked = b.releases.start(ref)
+		// members rolled out before the release was tracked, e.g. updated before this deployment got the new sha
+		sort.Slice(members, func(i, j int) bool { return members[i].Name < members[j].Name })
+		for _, member := range members {
+			if member.Name != deployment.Name && member.Annotations[hermodStateAnnotation] == hermodProgressingState {
+				tracked.record(member.Name, templates.PhaseStarted, now)
+			}
+		}
+	}
+
+	tracked.record(deployment.Name, outcome, now)
+	if helm := b.helmRelease(deployment); helm != nil && helm.Name == key {
+		tracked.helm = helm
+	}
+	if outcome == templates.PhaseFailed {
+		// the failure is sent on its own, posting the release as well would report it twice to channels without the release
+		b.sendRelease(tracked, nil)
+		return false
+	}
+	b.sendRelease(tracked, routes[outcome])
+
+	return true
+}
+
+// sendRelease will post the message of the release to channels it is not in yet and update it everywhere else
+func (b *deploymentInformer) sendRelease(tracked *release, channels []string) {
+	message := tracked.message()
+
+	for channel, ref := range tracked.messages {
+		if err := b.SlackClient.UpdateMessage(ref, message); err != nil {
+			reportReleaseError(channel, err)
+		}
+	}
+
+	for _, channel := range channels {
+		if _, ok := tracked.messages[channel]; ok {
+			continue
+		}
+		ref, err := b.SlackClient.PostMessage(channel, message)
+		if err != nil {
+			reportReleaseError(channel, err)
+			continue
+		}
+		tracked.messages[channel] = ref
+	}
+}
+
+func reportReleaseError(channel string, err error) {
+	message := fmt.Sprintf("failed to send slack release message to %s: %v", channel, err)
+	log.Error(message)
+	sentry.CaptureMessage(message)
+}
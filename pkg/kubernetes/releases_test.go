@@ -0,0 +1,118 @@
+package kubernetes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/uswitch/hermod/pkg/slack"
+)
+
+func TestReleaseKey(t *testing.T) {
+	b := newGitOpsInformer()
+
+	tests := []struct {
+		name           string
+		annotations    map[string]string
+		labels         map[string]string
+		expectedOutput string
+	}{
+		{
+			name:           "release annotation",
+			annotations:    map[string]string{"hermod.uswitch.com/release": "v1.2.0", "hermod.uswitch.com/gitsha": "abc123"},
+			labels:         map[string]string{"app.kubernetes.io/managed-by": "Helm", "app.kubernetes.io/instance": "api"},
+			expectedOutput: "v1.2.0",
+		},
+		{
+			name:           "helm release",
+			annotations:    map[string]string{"hermod.uswitch.com/gitsha": "abc123"},
+			labels:         map[string]string{"app.kubernetes.io/managed-by": "Helm", "app.kubernetes.io/instance": "api"},
+			expectedOutput: "api",
+		},
+		{
+			name:           "legacy helm release",
+			labels:         map[string]string{"heritage": "Helm", "release": "api"},
+			expectedOutput: "api",
+		},
+		{
+			name:           "instance label without helm",
+			annotations:    map[string]string{"hermod.uswitch.com/gitsha": "abc123"},
+			labels:         map[string]string{"app.kubernetes.io/instance": "api"},
+			expectedOutput: "abc123",
+		},
+		{
+			name:           "flux kustomization",
+			labels:         map[string]string{"kustomize.toolkit.fluxcd.io/name": "apps", "kustomize.toolkit.fluxcd.io/namespace": "flux-system"},
+			expectedOutput: "5f4c3b2a1908d7e6f5a4b3c2d1e0f9a8b7c6d5e4",
+		},
+		{
+			name: "no release key",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deployment := testDeployment("", tt.annotations, tt.labels)
+			if output := b.releaseKey(deployment); output != tt.expectedOutput {
+				t.Errorf("releaseKey() = %v, expectedOutput %v", output, tt.expectedOutput)
+			}
+		})
+	}
+}
+
+func TestReleaseOutcome(t *testing.T) {
+	now := time.Date(2021, 6, 5, 6, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		outcomes       [][2]string
+		expectedOutput string
+		expectedColor  string
+	}{
+		{
+			name:           "in progress",
+			outcomes:       [][2]string{{"web", "succeeded"}, {"worker", "started"}},
+			expectedOutput: "started",
+			expectedColor:  slack.OrangeColor,
+		},
+		{
+			name:           "every deployment succeeded",
+			outcomes:       [][2]string{{"web", "succeeded"}, {"worker", "succeeded"}},
+			expectedOutput: "succeeded",
+			expectedColor:  slack.GreenColor,
+		},
+		{
+			name:           "one deployment failed",
+			outcomes:       [][2]string{{"web", "failed"}, {"worker", "succeeded"}},
+			expectedOutput: "failed",
+			expectedColor:  slack.RedColor,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := releases{}
+			tracked := r.start(releaseRef{namespace: "payments", key: "abc123"})
+			for _, outcome := range tt.outcomes {
+				tracked.record(outcome[0], outcome[1], now)
+			}
+			if output := tracked.outcome(); output != tt.expectedOutput {
+				t.Errorf("outcome() = %v, expectedOutput %v", output, tt.expectedOutput)
+			}
+			if color := tracked.message().Color; color != tt.expectedColor {
+				t.Errorf("message() color = %v, expectedOutput %v", color, tt.expectedColor)
+			}
+		})
+	}
+}
+
+func TestReleasesGet(t *testing.T) {
+	now := time.Date(2021, 6, 5, 6, 0, 0, 0, time.UTC)
+	ref := releaseRef{namespace: "payments", key: "abc123"}
+	r := releases{}
+	r.start(ref).record("web", "started", now)
+
+	if _, ok := r.get(ref, 10*time.Minute, now.Add(5*time.Minute)); !ok {
+		t.Errorf("get() = %v, expected the release within the window", ok)
+	}
+	if _, ok := r.get(ref, 10*time.Minute, now.Add(10*time.Minute)); ok {
+		t.Errorf("get() = %v, expected the release to be forgotten after the window", ok)
+	}
+}
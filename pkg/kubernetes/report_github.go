@@ -0,0 +1,201 @@
+package kubernetes
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/getsentry/sentry-go"
+	log "github.com/sirupsen/logrus"
+	"github.com/uswitch/hermod/pkg/github"
+	"github.com/uswitch/hermod/pkg/scm"
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+const (
+	hermodGithubDeploymentAnnotation = "hermod.uswitch.com/github-deployment-id"
+	hermodGithubCheckRunAnnotation   = "hermod.uswitch.com/github-check-run-id"
+)
+
+// GithubOptions configures how rollouts are reported to GitHub
+type GithubOptions struct {
+	// Deployments creates GitHub Deployments and Deployment Statuses for each rollout
+	Deployments bool
+	// CommitStatus sets a commit status on the deployed sha
+	CommitStatus bool
+	// CheckRuns reports a check run instead of a commit status, this requires a GitHub App token
+	CheckRuns bool
+	// CommitDetails lists the commits between the previous and new revision in notifications
+	CommitDetails bool
+	// StatusContext is the prefix of the commit status context or check run name, the cluster name is appended to it
+	StatusContext string
+}
+
+// githubEnvironment is the name of the GitHub deployment environment for a namespace
+func githubEnvironment(namespace string) string {
+	if getClusterName() == "" {
+		return namespace
+	}
+	return fmt.Sprintf("%s/%s", getClusterName(), namespace)
+}
+
+// githubStatusContext is the name of the commit status or check run, e.g. hermod/prod-cluster
+func githubStatusContext(options GithubOptions) string {
+	if getClusterName() == "" {
+		return options.StatusContext
+	}
+	return fmt.Sprintf("%s/%s", options.StatusContext, getClusterName())
+}
+
+// githubRepository returns the GitHub repository and sha a deployment was built from, if they are known and hosted on GitHub
+func (b *deploymentInformer) githubRepository(deployment *appsv1.Deployment) (github.Repository, string, bool) {
+	repoURL, sha := b.gitRevision(deployment)
+	if repoURL == "" || sha == "" {
+		return github.Repository{}, "", false
+	}
+
+	// repositories of other providers could share the path of an unrelated GitHub repository
+	providerName, err := b.SCMResolver.Name(repoURL, deployment.GetAnnotations()[hermodSCMAnnotation])
+	if err != nil || !scm.IsGitHub(providerName) {
+		return github.Repository{}, "", false
+	}
+
+	repo, err := github.ParseRepository(repoURL)
+	if err != nil {
+		log.Warnf("cannot report deployment `%s` in `%s` namespace to github: %v", deployment.Name, deployment.Namespace, err)
+		return github.Repository{}, "", false
+	}
+
+	return repo, sha, true
+}
+
+// reportedToGithub will tell whether rollouts of the deployment are reported to GitHub, which they are
+// whether or not any Slack channel is set for them
+func (b *deploymentInformer) reportedToGithub(deployment *appsv1.Deployment) bool {
+	options := b.githubOptions()
+	if b.GithubClient == nil || !(options.Deployments || options.CommitStatus || options.CheckRuns) {
+		return false
+	}
+
+	if tracked, err := b.isTracked(deployment); err != nil || !tracked {
+		return false
+	}
+
+	_, _, ok := b.githubRepository(deployment)
+	return ok
+}
+
+// startGithubReport reports the start of a rollout to GitHub and returns the annotations recording what was created
+func (b *deploymentInformer) startGithubReport(deployment *appsv1.Deployment, description string) map[string]string {
+	annotations := map[string]string{}
+	if b.GithubClient == nil {
+		return annotations
+	}
+	options := b.githubOptions()
+
+	// always reset the recorded ids so a later result is never reported against a previous rollout
+	if options.Deployments {
+		annotations[hermodGithubDeploymentAnnotation] = ""
+	}
+	if options.CheckRuns {
+		annotations[hermodGithubCheckRunAnnotation] = ""
+	}
+
+	repo, sha, ok := b.githubRepository(deployment)
+	if !ok {
+		return annotations
+	}
+
+	if options.Deployments {
+		id, err := b.GithubClient.CreateDeployment(b.Context, repo, sha, githubEnvironment(deployment.Namespace), description)
+		if err != nil {
+			reportGithubError("failed to create github deployment", err)
+		} else {
+			annotations[hermodGithubDeploymentAnnotation] = strconv.FormatInt(id, 10)
+
+			err = b.GithubClient.CreateDeploymentStatus(b.Context, repo, id, github.StateInProgress, description)
+			if err != nil {
+				reportGithubError("failed to update github deployment", err)
+			}
+		}
+	}
+
+	if options.CheckRuns {
+		id, err := b.GithubClient.CreateCheckRun(b.Context, repo, sha, githubStatusContext(options), github.CheckRunOutput{Title: "Rollout in progress", Summary: description})
+		if err != nil {
+			reportGithubError("failed to create github check run", err)
+		} else {
+			annotations[hermodGithubCheckRunAnnotation] = strconv.FormatInt(id, 10)
+		}
+	} else if options.CommitStatus {
+		err := b.GithubClient.CreateCommitStatus(b.Context, repo, sha, github.StatusPending, githubStatusContext(options), description)
+		if err != nil {
+			reportGithubError("failed to set github commit status", err)
+		}
+	}
+
+	return annotations
+}
+
+// finishGithubReport reports the result of a rollout to GitHub, summary is included in the check run output
+func (b *deploymentInformer) finishGithubReport(deployment *appsv1.Deployment, success bool, description, summary string) {
+	if b.GithubClient == nil {
+		return
+	}
+	options := b.githubOptions()
+
+	repo, sha, ok := b.githubRepository(deployment)
+	if !ok {
+		return
+	}
+
+	state, status, conclusion, title := github.StateFailure, github.StatusFailure, github.ConclusionFailure, "Rollout failed"
+	if success {
+		state, status, conclusion, title = github.StateSuccess, github.StatusSuccess, github.ConclusionSuccess, "Rollout successful"
+	}
+
+	if options.Deployments {
+		if id, ok := recordedGithubID(deployment, hermodGithubDeploymentAnnotation); ok {
+			err := b.GithubClient.CreateDeploymentStatus(b.Context, repo, id, state, description)
+			if err != nil {
+				reportGithubError("failed to update github deployment", err)
+			}
+		}
+	}
+
+	if options.CheckRuns {
+		if id, ok := recordedGithubID(deployment, hermodGithubCheckRunAnnotation); ok {
+			err := b.GithubClient.CompleteCheckRun(b.Context, repo, id, conclusion, github.CheckRunOutput{Title: title, Summary: summary})
+			if err != nil {
+				reportGithubError("failed to complete github check run", err)
+			}
+		}
+	} else if options.CommitStatus {
+		err := b.GithubClient.CreateCommitStatus(b.Context, repo, sha, status, githubStatusContext(options), description)
+		if err != nil {
+			reportGithubError("failed to set github commit status", err)
+		}
+	}
+}
+
+// recordedGithubID reads the id of a GitHub object recorded in an annotation of the deployment
+func recordedGithubID(deployment *appsv1.Deployment, annotation string) (int64, bool) {
+	value := deployment.GetAnnotations()[annotation]
+	if value == "" {
+		log.Debugf("no %s recorded for deployment `%s` in `%s` namespace", annotation, deployment.Name, deployment.Namespace)
+		return 0, false
+	}
+
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		log.Errorf("invalid %s %q on deployment `%s` in `%s` namespace: %v", annotation, value, deployment.Name, deployment.Namespace, err)
+		return 0, false
+	}
+
+	return id, true
+}
+
+func reportGithubError(msg string, err error) {
+	message := fmt.Sprintf("%s: %v", msg, err)
+	log.Error(message)
+	sentry.CaptureMessage(message)
+}
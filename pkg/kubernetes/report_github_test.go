@@ -0,0 +1,124 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/uswitch/hermod/pkg/github"
+)
+
+func TestReportedToGithub(t *testing.T) {
+	git := map[string]string{"hermod.uswitch.com/gitrepo": "https://github.com/uswitch/hermod", "hermod.uswitch.com/gitsha": "abc123"}
+
+	tests := []struct {
+		name           string
+		client         *github.Client
+		options        GithubOptions
+		namespace      string
+		annotations    map[string]string
+		expectedOutput bool
+	}{
+		{
+			name:           "github deployments",
+			client:         &github.Client{},
+			options:        GithubOptions{Deployments: true},
+			namespace:      "payments",
+			annotations:    git,
+			expectedOutput: true,
+		},
+		{
+			name:        "no github client",
+			options:     GithubOptions{Deployments: true},
+			namespace:   "payments",
+			annotations: git,
+		},
+		{
+			name:        "github client for commit details only",
+			client:      &github.Client{},
+			options:     GithubOptions{CommitDetails: true},
+			namespace:   "payments",
+			annotations: git,
+		},
+		{
+			name:        "excluded namespace",
+			client:      &github.Client{},
+			options:     GithubOptions{CommitStatus: true},
+			namespace:   "kube-system",
+			annotations: git,
+		},
+		{
+			name:        "repository url without a repository name",
+			client:      &github.Client{},
+			options:     GithubOptions{CommitStatus: true},
+			namespace:   "payments",
+			annotations: map[string]string{"hermod.uswitch.com/gitrepo": "https://github.com/uswitch", "hermod.uswitch.com/gitsha": "abc123"},
+		},
+		{
+			name:        "gitlab repository",
+			client:      &github.Client{},
+			options:     GithubOptions{Deployments: true, CommitStatus: true},
+			namespace:   "payments",
+			annotations: map[string]string{"hermod.uswitch.com/gitrepo": "https://gitlab.com/uswitch/hermod", "hermod.uswitch.com/gitsha": "abc123"},
+		},
+		{
+			name:      "no git annotations",
+			client:    &github.Client{},
+			options:   GithubOptions{CheckRuns: true},
+			namespace: "payments",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := testSettings()
+			settings.GithubClient, settings.GithubOptions, settings.ExcludeNamespaces = tt.client, tt.options, []string{"kube-*"}
+			b := &deploymentInformer{Settings: settings}
+			deployment := testDeployment(tt.namespace, tt.annotations, nil)
+			if output := b.reportedToGithub(deployment); output != tt.expectedOutput {
+				t.Errorf("reportedToGithub() = %v, expectedOutput %v", output, tt.expectedOutput)
+			}
+		})
+	}
+}
+
+func TestGithubRepository(t *testing.T) {
+	tests := []struct {
+		name           string
+		annotations    map[string]string
+		expectedOutput github.Repository
+		expectedOk     bool
+	}{
+		{
+			name:           "github repository",
+			annotations:    map[string]string{"hermod.uswitch.com/gitrepo": "https://github.com/uswitch/hermod", "hermod.uswitch.com/gitsha": "abc123"},
+			expectedOutput: github.Repository{Owner: "uswitch", Name: "hermod"},
+			expectedOk:     true,
+		},
+		{
+			name:           "github enterprise repository",
+			annotations:    map[string]string{"hermod.uswitch.com/gitrepo": "https://git.example.com/uswitch/hermod", "hermod.uswitch.com/gitsha": "abc123"},
+			expectedOutput: github.Repository{Owner: "uswitch", Name: "hermod"},
+			expectedOk:     true,
+		},
+		{
+			name:        "gitlab repository",
+			annotations: map[string]string{"hermod.uswitch.com/gitrepo": "https://gitlab.com/uswitch/hermod", "hermod.uswitch.com/gitsha": "abc123"},
+		},
+		{
+			name:        "repository of another provider by annotation",
+			annotations: map[string]string{"hermod.uswitch.com/gitrepo": "https://git.example.com/uswitch/hermod", "hermod.uswitch.com/gitsha": "abc123", hermodSCMAnnotation: "gitea"},
+		},
+		{
+			name:        "no sha",
+			annotations: map[string]string{"hermod.uswitch.com/gitrepo": "https://github.com/uswitch/hermod"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &deploymentInformer{Settings: testSettings()}
+			deployment := testDeployment("payments", tt.annotations, nil)
+			output, _, ok := b.githubRepository(deployment)
+			if ok != tt.expectedOk || output != tt.expectedOutput {
+				t.Errorf("githubRepository() = %v, %v, expectedOutput %v, %v", output, ok, tt.expectedOutput, tt.expectedOk)
+			}
+		})
+	}
+}
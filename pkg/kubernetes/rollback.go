@@ -0,0 +1,85 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	revisionHistory = "deployment.kubernetes.io/revision-history"
+)
+
+// rollback describes a rollout that brought back the pod template of an earlier revision
+type rollback struct {
+	// revision the pod template was originally rolled out as
+	revision string
+	images   []string
+}
+
+// detectRollback will check whether the current replicaset of a deployment was reused from an earlier revision,
+// which is what happens on `kubectl rollout undo`
+func detectRollback(ctx context.Context, client kubernetes.Interface, deployment *appsv1.Deployment) (rollback, bool) {
+	replicaSets, err := getDeploymentReplicaSets(ctx, client, deployment)
+	if err != nil {
+		log.Warnf("failed to get the replicasets of deployment `%s` in `%s` namespace: %v", deployment.Name, deployment.Namespace, err)
+		return rollback{}, false
+	}
+
+	currentRS, previousRS := currentAndPreviousReplicaSets(replicaSets, deployment.Annotations[revision])
+	if currentRS == nil {
+		return rollback{}, false
+	}
+
+	var rolledBackTo string
+	if history := currentRS.Annotations[revisionHistory]; history != "" {
+		rolledBackTo = latestRevision(strings.Split(history, ","))
+	} else if previousRS != nil && currentRS.CreationTimestamp.Before(&previousRS.CreationTimestamp) {
+		// the revision history is only kept by the deployment controller, fall back to the replicaset being older than its predecessor
+		rolledBackTo = "an earlier revision"
+	}
+
+	if rolledBackTo == "" {
+		return rollback{}, false
+	}
+
+	var images []string
+	for _, container := range currentRS.Spec.Template.Spec.Containers {
+		images = append(images, container.Image)
+	}
+
+	return rollback{revision: rolledBackTo, images: images}, true
+}
+
+// latestRevision will return the highest of the given revision numbers
+func latestRevision(revisions []string) string {
+	var latest int64
+	for _, r := range revisions {
+		number, err := strconv.ParseInt(strings.TrimSpace(r), 10, 64)
+		if err == nil && number > latest {
+			latest = number
+		}
+	}
+
+	if latest == 0 {
+		return ""
+	}
+	return strconv.FormatInt(latest, 10)
+}
+
+func (r rollback) String() string {
+	target := r.revision
+	if _, err := strconv.ParseInt(r.revision, 10, 64); err == nil {
+		target = "revision " + r.revision
+	}
+
+	if len(r.images) == 0 {
+		return target
+	}
+	return fmt.Sprintf("%s (image `%s`)", target, strings.Join(r.images, "`, `"))
+}
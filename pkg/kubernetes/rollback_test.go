@@ -0,0 +1,62 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDetectRollback(t *testing.T) {
+	deployment := appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test", UID: "uid", Annotations: map[string]string{revision: "4"}},
+		Spec:       appsv1.DeploymentSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}}},
+	}
+	isController := true
+	replicaSet := func(name, revisionNumber, history, image string) *appsv1.ReplicaSet {
+		return &appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				Namespace:       "test",
+				Labels:          map[string]string{"foo": "bar"},
+				Annotations:     map[string]string{revision: revisionNumber, revisionHistory: history},
+				OwnerReferences: []metav1.OwnerReference{{UID: "uid", Controller: &isController}},
+			},
+			Spec: appsv1.ReplicaSetSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: image}}}}},
+		}
+	}
+
+	tests := []struct {
+		name           string
+		replicaSets    []*appsv1.ReplicaSet
+		expectedOutput string
+		expectedOk     bool
+	}{
+		{
+			name:        "new revision",
+			replicaSets: []*appsv1.ReplicaSet{replicaSet("a", "2", "", "app:1"), replicaSet("b", "3", "", "app:2"), replicaSet("c", "4", "", "app:3")},
+		},
+		{
+			name:           "rolled back",
+			replicaSets:    []*appsv1.ReplicaSet{replicaSet("a", "4", "1,2", "app:1"), replicaSet("b", "3", "", "app:2")},
+			expectedOutput: "revision 2 (image `app:1`)",
+			expectedOk:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := k8sfake.NewSimpleClientset()
+			for _, rs := range tt.replicaSets {
+				client.Tracker().Add(rs)
+			}
+
+			output, ok := detectRollback(context.Background(), client, &deployment)
+			if ok != tt.expectedOk || (ok && output.String() != tt.expectedOutput) {
+				t.Errorf("detectRollback() = %v, %v, expectedOutput %v, %v", output, ok, tt.expectedOutput, tt.expectedOk)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+
+	"github.com/getsentry/sentry-go"
+	log "github.com/sirupsen/logrus"
+	"github.com/uswitch/hermod/pkg/slack"
+	"github.com/uswitch/hermod/pkg/templates"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	hermodSlackRoutesAnnotation = "hermod.uswitch.com/slack-routes"
+	hermodIgnoreAnnotation      = "hermod.uswitch.com/ignore"
+)
+
+// rollout outcomes messages are routed by
+var outcomes = []string{templates.PhaseStarted, templates.PhaseSucceeded, templates.PhaseFailed}
+
+// slackRoutes maps each outcome of a rollout to the Slack channels its messages are sent to
+type slackRoutes map[string][]string
+
+// getSlackRoutes will return where to send messages about the deployment.
+// The `hermod.uswitch.com/slack` channels receive every outcome unless `hermod.uswitch.com/slack-routes` routes it elsewhere.
+func (b *deploymentInformer) getSlackRoutes(deployment *appsv1.Deployment) (slackRoutes, error) {
+	tracked, err := b.isTracked(deployment)
+	if err != nil || !tracked {
+		return slackRoutes{}, err
+	}
+
+	channels, err := b.getSlackChannels(deployment)
+	if err != nil {
+		return nil, err
+	}
+
+	routes, err := b.getSetting(deployment, hermodSlackRoutesAnnotation)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseSlackRoutes(channels, routes)
+}
+
+// getSlackChannels will return the channels of the deployment, falling back to those of its namespace
+// and then to the default channel when the namespace is opted in by label
+func (b *deploymentInformer) getSlackChannels(deployment *appsv1.Deployment) (string, error) {
+	channels, err := b.getSetting(deployment, hermodSlackChannelAnnotation)
+	defaultChannel := b.defaultSlackChannel()
+	if err != nil || channels != "" || defaultChannel == "" {
+		return channels, err
+	}
+
+	namespace, err := getNamespace(deployment.Namespace, b.namespaceIndexer)
+	if err != nil {
+		return "", err
+	}
+	if namespace.Labels[hermodEnabledLabel] == "true" || (b.NamespaceSelector != nil && !b.NamespaceSelector.Empty()) {
+		return defaultChannel, nil
+	}
+
+	return "", nil
+}
+
+// isTracked will tell whether the deployment is tracked at all: it is not ignored and its namespace
+// matches the namespace selector and none of the exclude patterns
+func (b *deploymentInformer) isTracked(deployment *appsv1.Deployment) (bool, error) {
+	if deployment.Annotations[hermodIgnoreAnnotation] == "true" {
+		return false, nil
+	}
+
+	for _, pattern := range b.ExcludeNamespaces {
+		if excluded, _ := path.Match(pattern, deployment.Namespace); excluded {
+			return false, nil
+		}
+	}
+
+	if b.NamespaceSelector == nil || b.NamespaceSelector.Empty() {
+		return true, nil
+	}
+
+	namespace, err := getNamespace(deployment.Namespace, b.namespaceIndexer)
+	if err != nil {
+		return false, err
+	}
+
+	return b.NamespaceSelector.Matches(labels.Set(namespace.Labels)), nil
+}
+
+// parseSlackRoutes will route every outcome to the comma separated channels, unless the JSON routes,
+// e.g. `{"failed": ["team-alerts", "incidents"]}`, route it elsewhere
+func parseSlackRoutes(channels, routes string) (slackRoutes, error) {
+	parsed := slackRoutes{}
+	for _, outcome := range outcomes {
+		parsed[outcome] = splitList(channels)
+	}
+
+	if routes == "" {
+		return parsed, nil
+	}
+
+	var configured map[string][]string
+	if err := json.Unmarshal([]byte(routes), &configured); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %v", hermodSlackRoutesAnnotation, err)
+	}
+
+	for outcome, channels := range configured {
+		if _, ok := parsed[outcome]; !ok {
+			return nil, fmt.Errorf("invalid %s annotation: unknown outcome %q, expected one of %v", hermodSlackRoutesAnnotation, outcome, outcomes)
+		}
+		parsed[outcome] = channels
+	}
+
+	return parsed, nil
+}
+
+// tracked will tell whether any message is sent at all
+func (r slackRoutes) tracked() bool {
+	for _, channels := range r {
+		if len(channels) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sendSlackMessage will send the message to every channel the outcome is routed to
+func (b *deploymentInformer) sendSlackMessage(routes slackRoutes, outcome string, message slack.Message) {
+	for _, channel := range routes[outcome] {
+		b.sendToChannel(channel, message)
+	}
+}
+
+func (b *deploymentInformer) sendToChannel(channel string, message slack.Message) {
+	err := b.SlackClient.SendMessage(channel, message)
+	if err != nil {
+		message := fmt.Sprintf("failed to send slack message: %v", err)
+		log.Error(message)
+		sentry.CaptureMessage(message)
+	}
+}
@@ -0,0 +1,166 @@
+package kubernetes
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestParseSlackRoutes(t *testing.T) {
+	tests := []struct {
+		name           string
+		channels       string
+		routes         string
+		expectedOutput slackRoutes
+		expectError    bool
+	}{
+		{
+			name:           "single channel",
+			channels:       "deploys",
+			expectedOutput: slackRoutes{"started": {"deploys"}, "succeeded": {"deploys"}, "failed": {"deploys"}},
+		},
+		{
+			name:           "failures routed elsewhere",
+			channels:       "deploys",
+			routes:         `{"failed": ["team-alerts", "incidents"]}`,
+			expectedOutput: slackRoutes{"started": {"deploys"}, "succeeded": {"deploys"}, "failed": {"team-alerts", "incidents"}},
+		},
+		{
+			name:           "routes only",
+			routes:         `{"failed": ["team-alerts"]}`,
+			expectedOutput: slackRoutes{"started": nil, "succeeded": nil, "failed": {"team-alerts"}},
+		},
+		{
+			name:        "unknown outcome",
+			channels:    "deploys",
+			routes:      `{"failure": ["team-alerts"]}`,
+			expectError: true,
+		},
+		{
+			name:        "invalid json",
+			routes:      `failed=team-alerts`,
+			expectError: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := parseSlackRoutes(tt.channels, tt.routes)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("parseSlackRoutes() error = %v, expectError %v", err, tt.expectError)
+			}
+			if !reflect.DeepEqual(output, tt.expectedOutput) {
+				t.Errorf("parseSlackRoutes() = %v, expectedOutput %v", output, tt.expectedOutput)
+			}
+		})
+	}
+}
+
+func TestGetSlackChannels(t *testing.T) {
+	indexer := newNamespaceIndexer(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "annotated", Annotations: map[string]string{hermodSlackChannelAnnotation: "team-deploys"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "labelled", Labels: map[string]string{hermodEnabledLabel: "true"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "untracked"}},
+	)
+
+	tests := []struct {
+		name           string
+		namespace      string
+		annotations    map[string]string
+		expectedOutput string
+	}{
+		{
+			name:           "namespace channel",
+			namespace:      "annotated",
+			expectedOutput: "team-deploys",
+		},
+		{
+			name:           "deployment overrides namespace",
+			namespace:      "annotated",
+			annotations:    map[string]string{hermodSlackChannelAnnotation: "payments-deploys"},
+			expectedOutput: "payments-deploys",
+		},
+		{
+			name:           "default channel for labelled namespace",
+			namespace:      "labelled",
+			expectedOutput: "deploys",
+		},
+		{
+			name:      "untracked namespace",
+			namespace: "untracked",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &deploymentInformer{namespaceIndexer: indexer, Settings: Settings{DefaultSlackChannel: "deploys"}}
+			deployment := testDeployment(tt.namespace, tt.annotations, nil)
+			output, err := b.getSlackChannels(deployment)
+			if err != nil {
+				t.Fatalf("getSlackChannels() error = %v", err)
+			}
+			if output != tt.expectedOutput {
+				t.Errorf("getSlackChannels() = %v, expectedOutput %v", output, tt.expectedOutput)
+			}
+		})
+	}
+}
+
+func TestIsTracked(t *testing.T) {
+	indexer := newNamespaceIndexer(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "payments", Labels: map[string]string{"team": "payments"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "search", Labels: map[string]string{"team": "search"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "payments-preview", Labels: map[string]string{"team": "payments"}}},
+	)
+
+	selector, _ := labels.Parse("team=payments")
+
+	tests := []struct {
+		name           string
+		namespace      string
+		annotations    map[string]string
+		selector       labels.Selector
+		expectedOutput bool
+	}{
+		{
+			name:           "no selector",
+			namespace:      "search",
+			expectedOutput: true,
+		},
+		{
+			name:           "matching selector",
+			namespace:      "payments",
+			selector:       selector,
+			expectedOutput: true,
+		},
+		{
+			name:      "not matching selector",
+			namespace: "search",
+			selector:  selector,
+		},
+		{
+			name:      "excluded namespace",
+			namespace: "payments-preview",
+			selector:  selector,
+		},
+		{
+			name:        "ignored deployment",
+			namespace:   "payments",
+			annotations: map[string]string{hermodIgnoreAnnotation: "true"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &deploymentInformer{namespaceIndexer: indexer, Settings: Settings{NamespaceSelector: tt.selector, ExcludeNamespaces: []string{"kube-*", "*-preview"}}}
+			deployment := testDeployment(tt.namespace, tt.annotations, nil)
+			output, err := b.isTracked(deployment)
+			if err != nil {
+				t.Fatalf("isTracked() error = %v", err)
+			}
+			if output != tt.expectedOutput {
+				t.Errorf("isTracked() = %v, expectedOutput %v", output, tt.expectedOutput)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	newReplicaSetAvailableReason = "NewReplicaSetAvailable"
+)
+
+// DeploymentStatus will describe the current rollout state of a deployment tracked by Hermod
+func (b *deploymentInformer) DeploymentStatus(ctx context.Context, namespace, name string) (string, error) {
+	if b.namespaceIndexer == nil {
+		return "", fmt.Errorf("namespace cache has not synced yet")
+	}
+
+	deployment, err := b.getDeployment(namespace, name)
+	if err != nil {
+		return "", err
+	}
+
+	b.settingsLock.RLock()
+	defer b.settingsLock.RUnlock()
+
+	routes, err := b.getSlackRoutes(deployment)
+	if err != nil || !routes.tracked() {
+		return "", fmt.Errorf("deployment `%s` in `%s` namespace is not tracked by hermod", name, namespace)
+	}
+
+	lastOutcome := deployment.Annotations[hermodStateAnnotation]
+	if lastOutcome == "" {
+		lastOutcome = "none recorded"
+	}
+
+	var replicas int32 = 1
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+
+	lines := []string{
+		fmt.Sprintf("*Deployment `%s` in `%s` namespace on `%s` cluster*", deployment.Name, deployment.Namespace, getClusterName()),
+		fmt.Sprintf("*Rollout:* %s", rolloutState(deployment)),
+		fmt.Sprintf("*Revision:* %s", deployment.Annotations[revision]),
+		fmt.Sprintf("*Replicas:* `%d/%d` ready, `%d` updated", deployment.Status.ReadyReplicas, replicas, deployment.Status.UpdatedReplicas),
+		fmt.Sprintf("*Last outcome:* %s", lastOutcome),
+	}
+
+	_, errorList, err := getPodErrors(ctx, b.client, namespace, deployment)
+	if err != nil {
+		return "", err
+	}
+	if len(errorList) > 0 {
+		lines = append(lines, "*Current errors:*")
+		for _, e := range errorList {
+			lines = append(lines, e.String())
+		}
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// rolloutState will summarise the Progressing condition of a deployment
+func rolloutState(deployment *appsv1.Deployment) string {
+	if deployment.Spec.Paused {
+		return "paused"
+	}
+
+	if deployment.Generation != deployment.Status.ObservedGeneration {
+		return "waiting for the deployment controller"
+	}
+
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type != appsv1.DeploymentProgressing {
+			continue
+		}
+
+		switch {
+		case condition.Reason == progressDeadlineExceededReason:
+			return "failed, progress deadline exceeded"
+		case condition.Reason == newReplicaSetAvailableReason && condition.Status == corev1.ConditionTrue:
+			return "complete"
+		default:
+			return "in progress"
+		}
+	}
+
+	return "unknown"
+}
@@ -0,0 +1,47 @@
+package kubernetes
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestRolloutState(t *testing.T) {
+	deployment := func(paused bool, conditions ...appsv1.DeploymentCondition) *appsv1.Deployment {
+		return &appsv1.Deployment{Spec: appsv1.DeploymentSpec{Paused: paused}, Status: appsv1.DeploymentStatus{Conditions: conditions}}
+	}
+	tests := []struct {
+		name           string
+		deployment     *appsv1.Deployment
+		expectedOutput string
+	}{
+		{
+			name:           "complete",
+			deployment:     deployment(false, appsv1.DeploymentCondition{Type: appsv1.DeploymentProgressing, Status: corev1.ConditionTrue, Reason: newReplicaSetAvailableReason}),
+			expectedOutput: "complete",
+		},
+		{
+			name:           "in progress",
+			deployment:     deployment(false, appsv1.DeploymentCondition{Type: appsv1.DeploymentProgressing, Status: corev1.ConditionTrue, Reason: "ReplicaSetUpdated"}),
+			expectedOutput: "in progress",
+		},
+		{
+			name:           "failed",
+			deployment:     deployment(false, appsv1.DeploymentCondition{Type: appsv1.DeploymentProgressing, Status: corev1.ConditionFalse, Reason: progressDeadlineExceededReason}),
+			expectedOutput: "failed, progress deadline exceeded",
+		},
+		{
+			name:           "paused",
+			deployment:     deployment(true),
+			expectedOutput: "paused",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if output := rolloutState(tt.deployment); output != tt.expectedOutput {
+				t.Errorf("rolloutState() = %v, expectedOutput %v", output, tt.expectedOutput)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	appsv1 "k8s.io/api/apps/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -11,13 +12,12 @@ import (
 	"k8s.io/client-go/kubernetes"
 )
 
-// addAnnotation will add the hermod specific annotation to the deployment
-func addAnnotation(ctx context.Context, client *kubernetes.Clientset, namespace string, newDeployment *appsv1.Deployment, state string) error {
+// addAnnotations will add the given hermod specific annotations to the deployment in a single patch
+func addAnnotations(ctx context.Context, client kubernetes.Interface, namespace string, newDeployment *appsv1.Deployment, annotations map[string]string) error {
 	patch := map[string]interface{}{
 		"metadata": map[string]map[string]string{
-			"annotations": {
-				hermodStateAnnotation: state,
-			}}}
+			"annotations": annotations,
+		}}
 
 	marshalledPatch, err := json.Marshal(patch)
 	if err != nil {
@@ -31,3 +31,54 @@ func addAnnotation(ctx context.Context, client *kubernetes.Clientset, namespace
 
 	return nil
 }
+
+// rollbackDeployment will restore the pod template of the given revision, the same way `kubectl rollout undo` does
+func rollbackDeployment(ctx context.Context, client kubernetes.Interface, deployment *appsv1.Deployment, toRevision string) error {
+	replicaSets, err := getDeploymentReplicaSets(ctx, client, deployment)
+	if err != nil {
+		return err
+	}
+
+	var target *appsv1.ReplicaSet
+	for i, rs := range replicaSets {
+		// a replicaset which was rolled back to before has been renumbered, its old numbers are kept in the revision history
+		if rs.Annotations[revision] == toRevision || containsRevision(rs.Annotations[revisionHistory], toRevision) {
+			target = &replicaSets[i]
+		}
+	}
+	if target == nil {
+		return fmt.Errorf("no replicaset found for revision %s", toRevision)
+	}
+
+	template := target.Spec.Template.DeepCopy()
+	delete(template.Labels, appsv1.DefaultDeploymentUniqueLabelKey)
+
+	patch := []map[string]interface{}{
+		{
+			"op":    "replace",
+			"path":  "/spec/template",
+			"value": template,
+		},
+	}
+
+	marshalledPatch, err := json.Marshal(patch)
+	if err != nil {
+		return fmt.Errorf("error marshalling data to json: %v", err)
+	}
+
+	_, err = client.AppsV1().Deployments(deployment.Namespace).Patch(ctx, deployment.Name, types.JSONPatchType, marshalledPatch, metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to roll back to revision %s: %v", toRevision, err)
+	}
+
+	return nil
+}
+
+func containsRevision(history, revisionNumber string) bool {
+	for _, r := range strings.Split(history, ",") {
+		if strings.TrimSpace(r) == revisionNumber {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package kubernetes
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestRollbackDeployment(t *testing.T) {
+	isController := true
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test", UID: "uid", Annotations: map[string]string{revision: "5"}},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
+			Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "app:broken"}}}},
+		},
+	}
+	rs := &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "test-abc",
+			Namespace:       "test",
+			Labels:          map[string]string{"foo": "bar", appsv1.DefaultDeploymentUniqueLabelKey: "abc"},
+			Annotations:     map[string]string{revision: "4", revisionHistory: "2"},
+			OwnerReferences: []metav1.OwnerReference{{UID: "uid", Controller: &isController}},
+		},
+		Spec: appsv1.ReplicaSetSpec{Template: corev1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"foo": "bar", appsv1.DefaultDeploymentUniqueLabelKey: "abc"}},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "app:good"}}},
+		}},
+	}
+	client := k8sfake.NewSimpleClientset(deployment, rs)
+
+	if err := rollbackDeployment(context.Background(), client, deployment, "3"); err == nil {
+		t.Errorf("rollbackDeployment() expected an error for an unknown revision")
+	}
+
+	// revision 2 was renumbered to 4 by an earlier rollback
+	if err := rollbackDeployment(context.Background(), client, deployment, "2"); err != nil {
+		t.Fatalf("rollbackDeployment() error = %v", err)
+	}
+
+	output, _ := client.AppsV1().Deployments("test").Get(context.Background(), "test", metav1.GetOptions{})
+	expectedOutput := corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"foo": "bar"}},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "app:good"}}},
+	}
+	if !reflect.DeepEqual(output.Spec.Template, expectedOutput) {
+		t.Errorf("rollbackDeployment() template = %v, expectedOutput %v", output.Spec.Template, expectedOutput)
+	}
+}
@@ -3,8 +3,10 @@ package kubernetes
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/rest"
@@ -17,6 +19,9 @@ const (
 
 	hermodAlertAnnotation        = "hermod.uswitch.com/alert"
 	hermodSlackChannelAnnotation = "hermod.uswitch.com/slack"
+	hermodSCMAnnotation          = "hermod.uswitch.com/scm"
+
+	hermodEnabledLabel = "hermod.uswitch.com/enabled"
 )
 
 func CreateClientConfig(kubeConfigPath string) (*rest.Config, error) {
@@ -30,24 +35,24 @@ func getClusterName() string {
 	return os.Getenv(clusterNameEnv)
 }
 
-func getSlackChannel(namespace string, indexer cache.Indexer) (string, error) {
-	nsResource, _, err := indexer.GetByKey(namespace)
+// getNamespace will return the namespace from the cache
+func getNamespace(namespace string, indexer cache.Indexer) (*corev1.Namespace, error) {
+	nsResource, exists, err := indexer.GetByKey(namespace)
 	if err != nil {
-		return "", fmt.Errorf("failed to get namespace from cache: %s", err)
+		return nil, fmt.Errorf("failed to get namespace from cache: %s", err)
 	}
-
-	nsAnnotations, err := meta.NewAccessor().Annotations(nsResource.(runtime.Object))
-	if err != nil {
-		return "", fmt.Errorf("failed to get annotations from namespace: %s", err)
+	if !exists {
+		return nil, fmt.Errorf("namespace %s not found", namespace)
 	}
 
-	return nsAnnotations[hermodSlackChannelAnnotation], nil
+	return nsResource.(*corev1.Namespace), nil
 }
 
-func getAlertLevel(deployment *appsv1.Deployment, indexer cache.Indexer) (string, error) {
-	alertLevel := deployment.GetAnnotations()[hermodAlertAnnotation]
+// getDeploymentOrNamespaceAnnotation will return the annotation of the deployment, falling back to its namespace when not set
+func getDeploymentOrNamespaceAnnotation(deployment *appsv1.Deployment, indexer cache.Indexer, annotation string) (string, error) {
+	value := deployment.GetAnnotations()[annotation]
 
-	if alertLevel == "" {
+	if value == "" {
 		nsResource, _, err := indexer.GetByKey(deployment.Namespace)
 		if err != nil {
 			return "", fmt.Errorf("failed to get namespace from cache: %s", err)
@@ -58,8 +63,20 @@ func getAlertLevel(deployment *appsv1.Deployment, indexer cache.Indexer) (string
 			return "", fmt.Errorf("failed to get annotations from namespace: %s", err)
 		}
 
-		alertLevel = nsAnnotations[hermodAlertAnnotation]
+		value = nsAnnotations[annotation]
+	}
+
+	return value, nil
+}
+
+// splitList will split a comma separated annotation value, dropping empty entries
+func splitList(value string) []string {
+	var entries []string
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry)
+		}
 	}
 
-	return alertLevel, nil
+	return entries
 }
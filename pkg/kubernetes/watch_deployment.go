@@ -5,31 +5,71 @@ import (
 	"fmt"
 	"os"
 	"sort"
+	"sync"
 	"time"
 
 	"github.com/getsentry/sentry-go"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	log "github.com/sirupsen/logrus"
+	"github.com/uswitch/hermod/pkg/github"
+	"github.com/uswitch/hermod/pkg/scm"
 	"github.com/uswitch/hermod/pkg/slack"
+	"github.com/uswitch/hermod/pkg/templates"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
 )
 
 type deploymentInformer struct {
-	store            cache.Store
-	controller       cache.Controller
-	client           *kubernetes.Clientset
-	SlackClient      *slack.Client
+	store         cache.Store
+	controller    cache.Controller
+	client        kubernetes.Interface
+	DynamicClient dynamic.Interface // reads Argo CD, Flux and Hermod custom resources
+	SlackClient   *slack.Client
+	Settings
+	settingsLock     sync.RWMutex                             // held for reading while deployments are handled, as Settings can be reloaded
+	ReloadSettings   func(file *ConfigFile) (Settings, error) // builds the settings from the flags of a changed config file
+	Policies         PolicyOptions
+	ConfigFile       string          // path of the config file to apply and watch for changes
+	SlackActions     bool            // offer buttons to act on failed deployments, requires the interactivity endpoint
 	Context          context.Context // TODO: Make it private if not needed in any other package
 	namespaceIndexer cache.Indexer
+	mutedSummaries   mutedSummaries
+	digests          digests
+	releases         releases
+	gitOpsRevisions  gitOpsRevisions
+	helmReleaseCache helmReleaseCache
+	policies         policies
+}
 
-	hermodGithubRepoAnnotation      string
-	hermodGithubCommitSHAAnnotation string
-	githubAnnotationWarning         bool
+// Settings are the options of the watcher given by flags, they are replaced as a whole when the flags of the config file change
+type Settings struct {
+	GithubClient        *github.Client
+	GithubOptions       GithubOptions
+	SCMResolver         *scm.Resolver
+	Templates           *templates.Templates
+	DefaultSlackChannel string          // channel of namespaces opted in by label without a channel of their own
+	NamespaceSelector   labels.Selector // only namespaces matching it are tracked, they are all opted in to the default channel
+	ExcludeNamespaces   []string        // patterns of namespaces never tracked, e.g. kube-*
+	MaintenanceWindows  []MaintenanceWindow
+	MaintenanceSummary  bool // summarise the messages muted during a maintenance window at its end
+	Digest              DigestOptions
+	Releases            ReleaseOptions
+	HelmReleases        bool // read the release secrets of deployments installed by Helm
+	GitOps              GitOpsOptions
+	AutoRollback        AutoRollbackOptions
+	EscalationDelay     time.Duration // how long a rollout has to keep failing before its escalation owners are mentioned
+	NotifyDeployer      bool          // send failure messages directly to whoever shipped the rollout
+
+	HermodGithubRepoAnnotation      string
+	HermodGithubCommitSHAAnnotation string
+	GithubAnnotationWarning         bool
+	DeployerEmailAnnotation         string
 }
 
 var (
@@ -45,6 +85,14 @@ var (
 		Name: "hermod_deployment_failed_total",
 		Help: "The total number of failed deployments processed",
 	})
+	rollbackDeploymentTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "hermod_deployment_rollback_total",
+		Help: "The total number of deployment rollbacks processed",
+	})
+	autoRollbackDeploymentTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "hermod_deployment_auto_rollback_total",
+		Help: "The total number of failed deployments rolled back automatically",
+	})
 )
 
 const (
@@ -56,18 +104,14 @@ const (
 	hermodFailState        = "fail"
 	hermodProgressingState = "progressing"
 
-	hermodAlertFailure = "failure"
-
 	failedCreateReason             = "FailedCreate"
 	progressDeadlineExceededReason = "ProgressDeadlineExceeded"
 )
 
-func NewDeploymentWatcher(client *kubernetes.Clientset, hermodGithubRepoAnnotation, hermodGithubCommitSHAAnnotation string, githubAnnotationWarning bool) *deploymentInformer {
+func NewDeploymentWatcher(client *kubernetes.Clientset, settings Settings) *deploymentInformer {
 	deploymentInformer := &deploymentInformer{
-		client:                          client,
-		hermodGithubRepoAnnotation:      hermodGithubRepoAnnotation,
-		hermodGithubCommitSHAAnnotation: hermodGithubCommitSHAAnnotation,
-		githubAnnotationWarning:         githubAnnotationWarning,
+		client:   client,
+		Settings: settings,
 	}
 	watcher := cache.NewListWatchFromClient(client.AppsV1().RESTClient(), "deployments", "", fields.Everything())
 	deploymentInformer.store, deploymentInformer.controller = cache.NewIndexerInformer(watcher, &appsv1.Deployment{}, time.Minute, deploymentInformer, cache.Indexers{})
@@ -84,26 +128,37 @@ func (b *deploymentInformer) OnUpdate(old, new interface{}) {
 	deploymentOld, _ := old.(*appsv1.Deployment)
 	deploymentNew, _ := new.(*appsv1.Deployment)
 
+	b.settingsLock.RLock()
+	defer b.settingsLock.RUnlock()
+
+	// persisting failures are escalated on any update of the deployment, including the resyncs of unchanged deployments,
+	// unless a new rollout replaces the failed one
+	if deploymentNew.Annotations[hermodEscalateAtAnnotation] != "" && deploymentOld.Annotations[revision] == deploymentNew.Annotations[revision] {
+		b.escalate(deploymentNew, time.Now())
+	}
+
 	// check if resourceversion are same
 	if deploymentOld.ResourceVersion == deploymentNew.ResourceVersion && deploymentNew.Annotations[hermodStateAnnotation] != hermodProgressingState {
 		return
 	}
 
-	// get slack channel name from namespace annotation
-	slackChannel, err := getSlackChannel(deploymentNew.Namespace, b.namespaceIndexer)
+	// get slack channels from the deployment and namespace annotations
+	routes, err := b.getSlackRoutes(deploymentNew)
 	if err != nil {
-		log.Errorf("failed to get slack channel for namespace: %s\n", err)
+		log.Errorf("failed to get slack channels for deployment: %s\n", err)
 		return
 	}
 
-	if slackChannel == "" {
+	// rollouts of deployments without a Slack channel are still reported to GitHub
+	notified := routes.tracked()
+	if !notified && !b.reportedToGithub(deploymentNew) {
 		log.Debugf("no hermod slack channel specified for namespace: %s\n", deploymentNew.Namespace)
 		return
 	}
 
 	updateDeployment := deploymentNew.DeepCopy()
 
-	alertLevel, err := getAlertLevel(deploymentNew, b.namespaceIndexer)
+	alertLevel, err := b.alertLevel(deploymentNew)
 	if err != nil {
 		log.Errorf("failed to get alert level for deployment: %s\n", err)
 		return
@@ -111,22 +166,37 @@ func (b *deploymentInformer) OnUpdate(old, new interface{}) {
 
 	// detecting the deployment rollout
 	if deploymentOld.GetAnnotations()[revision] != deploymentNew.GetAnnotations()[revision] && deploymentNew.Annotations[hermodStateAnnotation] != hermodProgressingState {
+		header := fmt.Sprintf("Rolling out %s", deploymentNew.Name)
 		msg := fmt.Sprintf("*Rolling out Deployment `%s` in namespace `%s` on `%s` cluster.*", deploymentNew.Name, deploymentNew.Namespace, getClusterName())
+		rolledBack, isRollback := detectRollback(b.Context, b.client, deploymentNew)
+		if isRollback {
+			header = fmt.Sprintf("Rolled back %s", deploymentNew.Name)
+			msg = fmt.Sprintf("*Rolled back Deployment `%s` in namespace `%s` on `%s` cluster to %s.*", deploymentNew.Name, deploymentNew.Namespace, getClusterName(), rolledBack)
+		}
 		log.Infof(msg)
-		err := addAnnotation(b.Context, b.client, deploymentNew.Namespace, updateDeployment, hermodProgressingState)
+
+		event := b.rolloutEvent(templates.PhaseStarted, deploymentNew, nil)
+		if isRollback {
+			event.RolledBackTo = rolledBack.String()
+		}
+
+		annotations := b.startGithubReport(deploymentNew, b.render(templates.SinkGithub, event, fmt.Sprintf("Rolling out Deployment %s to %s", deploymentNew.Name, githubEnvironment(deploymentNew.Namespace))))
+		annotations[hermodStateAnnotation] = hermodProgressingState
+		annotations[hermodEscalateAtAnnotation] = ""
+		annotations[hermodRolloutStartedAnnotation] = time.Now().UTC().Format(time.RFC3339)
+
+		err := addAnnotations(b.Context, b.client, deploymentNew.Namespace, updateDeployment, annotations)
 		if err != nil {
 			log.Errorf("failed to add annotation: %v", err)
 		}
 
-		// Send message if alertLevel isn't set to Failure only
-		if alertLevel != hermodAlertFailure {
+		// Send message if the alert level includes starts
+		if notified && b.shouldAlert(alertLevel, templates.PhaseStarted, deploymentNew) {
 			// send message to slack
-			err = b.SlackClient.SendMessage(slackChannel, msg, slack.OrangeColor)
-			if err != nil {
-				message := fmt.Sprintf("failed to send slack message: %v", err)
-				log.Errorf(message)
-				sentry.CaptureMessage(message)
-			}
+			b.notify(routes, templates.PhaseStarted, deploymentNew, b.notification(deploymentNew, b.renderHeader(templates.SinkSlack, event, header), b.render(templates.SinkSlack, event, msg), slack.OrangeColor))
+		}
+		if isRollback {
+			rollbackDeploymentTotal.Inc()
 		}
 		deploymentProcessedTotal.Inc()
 		return
@@ -152,7 +222,15 @@ func (b *deploymentInformer) OnUpdate(old, new interface{}) {
 		deploymentNew.Annotations[hermodStateAnnotation] != "" {
 
 		if deploymentNew.Annotations[hermodStateAnnotation] != hermodPassState {
-			err := addAnnotation(b.Context, b.client, deploymentNew.Namespace, updateDeployment, hermodPassState)
+			annotations := map[string]string{
+				hermodStateAnnotation:                  hermodPassState,
+				hermodLastSuccessfulRevisionAnnotation: deploymentNew.Annotations[revision],
+			}
+			if _, sha := b.gitRevision(deploymentNew); sha != "" {
+				annotations[hermodLastSuccessfulSHAAnnotation] = sha
+			}
+
+			err := addAnnotations(b.Context, b.client, deploymentNew.Namespace, updateDeployment, annotations)
 			if err != nil {
 				log.Errorf("failed to add annotation: %v", err)
 			}
@@ -160,15 +238,19 @@ func (b *deploymentInformer) OnUpdate(old, new interface{}) {
 			msg := fmt.Sprintf("*Rollout for Deployment `%s` in `%s` namespace on `%s` cluster is successful.*", deploymentNew.Name, deploymentNew.Namespace, getClusterName())
 			log.Infof(msg)
 
-			// Send message if alertLevel isn't set to Failure only
-			if alertLevel != hermodAlertFailure {
+			event := b.rolloutEvent(templates.PhaseSucceeded, deploymentNew, nil)
+
+			description := b.render(templates.SinkGithub, event, fmt.Sprintf("Rollout of Deployment %s to %s is successful", deploymentNew.Name, githubEnvironment(deploymentNew.Namespace)))
+			b.finishGithubReport(deploymentNew, true, description, description)
+
+			// Send message if the alert level includes successes
+			if notified && b.shouldAlert(alertLevel, templates.PhaseSucceeded, deploymentNew) {
 				// send message to slack
-				err = b.SlackClient.SendMessage(slackChannel, msg, slack.GreenColor)
-				if err != nil {
-					message := fmt.Sprintf("failed to send slack message: %v", err)
-					log.Error(message)
-					sentry.CaptureMessage(message)
-				}
+				header := b.renderHeader(templates.SinkSlack, event, fmt.Sprintf("Rolled out %s", deploymentNew.Name))
+				alert := b.notification(deploymentNew, header, b.render(templates.SinkSlack, event, msg), slack.GreenColor)
+				alert.Fields = rolloutFields(deploymentNew, time.Now())
+
+				b.notify(routes, templates.PhaseSucceeded, deploymentNew, alert)
 			}
 
 			successDeploymentTotal.Inc()
@@ -185,34 +267,79 @@ func (b *deploymentInformer) OnUpdate(old, new interface{}) {
 			deploymentNewConditions[len(deploymentNewConditions)-1].Reason == failedCreateReason) {
 
 		if deploymentNew.Annotations[hermodStateAnnotation] != hermodFailState {
-			err := addAnnotation(b.Context, b.client, deploymentNew.Namespace, updateDeployment, hermodFailState)
+			// Send message unless the alert level is none, failures which are not sent are not escalated either
+			alertFailure := notified && b.shouldAlert(alertLevel, templates.PhaseFailed, deploymentNew)
+
+			annotations := map[string]string{hermodStateAnnotation: hermodFailState}
+			if alertFailure {
+				for annotation, value := range b.escalationAnnotations(deploymentNew, time.Now()) {
+					annotations[annotation] = value
+				}
+			}
+
+			err := addAnnotations(b.Context, b.client, deploymentNew.Namespace, updateDeployment, annotations)
 			if err != nil {
 				log.Errorf("failed to add annotation: %v", err)
 			}
-			errorMsg, err := getErrorEvents(b.Context, b.client, deploymentNew.Namespace, updateDeployment)
+			rs, errorList, err := getPodErrors(b.Context, b.client, deploymentNew.Namespace, updateDeployment)
 			if err != nil {
 				log.Errorf("failed to get the error events: %v", err)
 			}
 
-			repo := deploymentNew.GetAnnotations()[b.hermodGithubRepoAnnotation]
-			sha := deploymentNew.GetAnnotations()[b.hermodGithubCommitSHAAnnotation]
-			if repo != "" && sha != "" {
-				commit := fmt.Sprintf("*Commit:* %s/commit/%s", repo, sha)
-				pullRequest := fmt.Sprintf("*Pull Request, if applicable:* %s/pulls/?q=%s", repo, sha)
+			event := b.rolloutEvent(templates.PhaseFailed, deploymentNew, errorList)
+
+			header := b.renderHeader(templates.SinkSlack, event, fmt.Sprintf("Failed to roll out %s", deploymentNew.Name))
+			alert := b.notification(deploymentNew, header, b.render(templates.SinkSlack, event, failureSummary(deploymentNew, rs, errorList)), slack.RedColor)
+			alert.Fields = rolloutFields(deploymentNew, time.Now())
+			alert.Errors = slackErrors(errorList)
+			alert.Mentions = b.ownerMentions(deploymentNew, hermodOwnersAnnotation)
+			repo, sha := b.gitRevision(deploymentNew)
+			if (repo == "" || sha == "") && b.GithubAnnotationWarning {
+				alert.Sections = append(alert.Sections, ":warning: *Could not find annotations"+fmt.Sprintf(" `%s` and `%s` ", b.HermodGithubRepoAnnotation, b.HermodGithubCommitSHAAnnotation)+", cannot link to Commit or Pull Request*")
+			}
+			if b.SlackActions {
+				alert.Actions = fmt.Sprintf("%s/%s", deploymentNew.Namespace, deploymentNew.Name)
+			}
 
-				errorMsg = errorMsg + fmt.Sprintf("\n\n%s\n\n%s", commit, pullRequest)
-			} else if b.githubAnnotationWarning {
-				errorMsg = errorMsg + "\n\n" + ":warning: *Could not find annotations" + fmt.Sprintf(" `%s` and `%s` ", b.hermodGithubRepoAnnotation, b.hermodGithubCommitSHAAnnotation) + ", cannot link to Commit or Pull Request*"
+			errorMsg := formatErrorEvents(deploymentNew, rs, errorList)
+			for _, link := range alert.Links {
+				errorMsg = errorMsg + fmt.Sprintf("\n\n*%s:* %s", link.Text, link.URL)
+			}
+			for _, section := range alert.Sections {
+				errorMsg = errorMsg + "\n\n" + section
 			}
 			log.Info(errorMsg)
 
-			// send message to slack
-			err = b.SlackClient.SendMessage(slackChannel, errorMsg, slack.RedColor)
+			b.finishGithubReport(deploymentNew, false, b.render(templates.SinkGithub, event, fmt.Sprintf("Rollout of Deployment %s to %s failed", deploymentNew.Name, githubEnvironment(deploymentNew.Namespace))), errorMsg)
+
+			if alertFailure {
+				b.notify(routes, templates.PhaseFailed, deploymentNew, alert)
+
+				if b.NotifyDeployer {
+					b.notifyDeployer(deploymentNew, alert)
+				}
+			}
+
+			rollbackMsg, rollback, err := b.autoRollback(deploymentNew, deploymentNewConditions[len(deploymentNewConditions)-1].Reason)
 			if err != nil {
-				message := fmt.Sprintf("failed to send slack message: %v", err)
+				message := fmt.Sprintf("failed to roll back deployment automatically: %v", err)
 				log.Error(message)
 				sentry.CaptureMessage(message)
 			}
+			if rollbackMsg != "" {
+				log.Info(rollbackMsg)
+			}
+			if rollbackMsg != "" && alertFailure {
+				rollbackEvent := event
+				rollbackEvent.Phase, rollbackEvent.AutoRollback = templates.PhaseAutoRollback, rollback
+
+				b.sendSlackMessage(routes, templates.PhaseFailed, slack.Message{
+					Header:  b.renderHeader(templates.SinkSlack, rollbackEvent, fmt.Sprintf("Automatic rollback of %s", deploymentNew.Name)),
+					Summary: b.render(templates.SinkSlack, rollbackEvent, rollbackMsg),
+					Context: rolloutContext(deploymentNew),
+					Color:   slack.OrangeColor,
+				})
+			}
 
 			failedDeploymentTotal.Inc()
 			return
@@ -221,16 +348,44 @@ func (b *deploymentInformer) OnUpdate(old, new interface{}) {
 }
 
 func (b *deploymentInformer) Run(ctx context.Context, stopCh <-chan os.Signal) {
+	// policies apply to the first rollouts seen
+	if b.ConfigFile != "" {
+		b.watchConfigFile(ctx)
+	}
+	if b.Policies.Watch {
+		b.watchPolicies(ctx)
+	}
+
 	go b.controller.Run(ctx.Done())
 	cache.WaitForCacheSync(ctx.Done(), b.controller.HasSynced)
 	log.Info("deployment cache controller synced")
 
 	b.namespaceIndexer = watchNamespaces(ctx, b.client)
 
+	// both are started whatever the settings, which can change when the config file is reloaded
+	go b.sendMaintenanceSummaries(ctx)
+	go b.updateDigests(ctx)
+
 	<-stopCh
 }
 
-func watchNamespaces(context context.Context, client *kubernetes.Clientset) cache.Indexer {
+// currentSettings returns the settings, for the goroutines which are not handling a deployment
+func (b *deploymentInformer) currentSettings() Settings {
+	b.settingsLock.RLock()
+	defer b.settingsLock.RUnlock()
+
+	return b.Settings
+}
+
+// replaceSettings will replace the settings once no deployment is being handled
+func (b *deploymentInformer) replaceSettings(settings Settings) {
+	b.settingsLock.Lock()
+	defer b.settingsLock.Unlock()
+
+	b.Settings = settings
+}
+
+func watchNamespaces(context context.Context, client kubernetes.Interface) cache.Indexer {
 	listWatcher := cache.NewListWatchFromClient(client.CoreV1().RESTClient(), "namespaces", "", fields.Everything())
 	indexer, informer := cache.NewIndexerInformer(listWatcher, &corev1.Namespace{}, 0, cache.ResourceEventHandlerFuncs{}, cache.Indexers{})
 
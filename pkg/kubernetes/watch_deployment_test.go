@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"reflect"
 	"testing"
+	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -174,7 +175,7 @@ func TestGetPods(t *testing.T) {
 	}
 }
 
-func TestGetErrorEvents(t *testing.T) {
+func TestGetPodErrors(t *testing.T) {
 	podo := corev1.Pod{
 		TypeMeta:   metav1.TypeMeta{Kind: "Pod", APIVersion: "v1"},
 		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test", Labels: map[string]string{"foo": "bar"}},
@@ -266,6 +267,7 @@ func TestGetErrorEvents(t *testing.T) {
 		{
 			name: "test 1",
 			args: args{
+				ctx:           context.Background(),
 				namespace:     "test",
 				newDeployment: &deployment,
 			},
@@ -274,9 +276,66 @@ func TestGetErrorEvents(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			output, _ := getErrorEvents(tt.args.ctx, client, tt.args.namespace, tt.args.newDeployment)
-			if ok := reflect.DeepEqual(output, tt.expectedOutput); !ok {
-				t.Errorf("getErrorEvents() = %v, expectedOutput %v", output, tt.expectedOutput)
+			rs, errorList, err := getPodErrors(tt.args.ctx, client, tt.args.namespace, tt.args.newDeployment)
+			if err != nil {
+				t.Fatalf("getPodErrors() error = %v", err)
+			}
+			if output := formatErrorEvents(tt.args.newDeployment, rs, errorList); output != tt.expectedOutput {
+				t.Errorf("formatErrorEvents() = %v, expectedOutput %v", output, tt.expectedOutput)
+			}
+		})
+	}
+}
+
+func TestOnUpdateEscalates(t *testing.T) {
+	indexer := newNamespaceIndexer(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "payments", Annotations: map[string]string{hermodSlackChannelAnnotation: "team"}}},
+	)
+
+	tests := []struct {
+		name           string
+		state          string
+		expectedOutput bool
+	}{
+		{
+			name:           "failing rollout whose status changed",
+			state:          hermodFailState,
+			expectedOutput: true,
+		},
+		{
+			name:  "rollout which passed since",
+			state: hermodPassState,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var replicas int32 = 2
+			old := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "payments", ResourceVersion: "10", Generation: 2, Annotations: map[string]string{
+					revision:                   "2",
+					hermodStateAnnotation:      tt.state,
+					hermodEscalateAtAnnotation: "2021-06-01T12:00:00Z",
+				}},
+				Spec: appsv1.DeploymentSpec{Replicas: &replicas},
+				Status: appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 3, ReadyReplicas: 1, Conditions: []appsv1.DeploymentCondition{
+					{Type: appsv1.DeploymentProgressing, Status: corev1.ConditionFalse, Reason: progressDeadlineExceededReason},
+				}},
+			}
+			// a pod of the failing rollout restarted
+			new := old.DeepCopy()
+			new.ResourceVersion = "11"
+			new.Status.ReadyReplicas = 0
+
+			client := k8sfake.NewSimpleClientset(old)
+			b := &deploymentInformer{Context: context.Background(), client: client, namespaceIndexer: indexer, Settings: Settings{EscalationDelay: 30 * time.Minute}}
+			b.OnUpdate(old, new)
+
+			escalated := false
+			for _, action := range client.Actions() {
+				escalated = escalated || action.GetVerb() == "patch"
+			}
+			if escalated != tt.expectedOutput {
+				t.Errorf("OnUpdate() escalated = %v, expectedOutput %v", escalated, tt.expectedOutput)
 			}
 		})
 	}
@@ -0,0 +1,214 @@
+package scm
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// supported source control providers
+const (
+	GitHub           = "github"
+	GitHubEnterprise = "github-enterprise"
+	GitLab           = "gitlab"
+	Bitbucket        = "bitbucket"
+	Gitea            = "gitea"
+)
+
+// Provider generates links into the web UI of a source control provider
+type Provider interface {
+	// CommitURL links to a single commit
+	CommitURL(repoURL, sha string) string
+	// CompareURL links to the changes between two commits
+	CompareURL(repoURL, from, to string) string
+	// MergeRequestSearchURL links to a search for the pull or merge requests containing the commit
+	MergeRequestSearchURL(repoURL, sha string) string
+	// MergeRequestName is what the provider calls a pull request
+	MergeRequestName() string
+}
+
+var providers = map[string]Provider{
+	GitHub:           github{},
+	GitHubEnterprise: github{},
+	GitLab:           gitlab{},
+	Bitbucket:        bitbucket{},
+	Gitea:            gitea{},
+}
+
+// Names lists the supported provider names
+func Names() []string {
+	var names []string
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Resolver selects the provider for a repository
+type Resolver struct {
+	hosts map[string]string
+}
+
+// NewResolver creates a Resolver, hosts maps repository hosts such as git.example.com to a provider name
+func NewResolver(hosts map[string]string) (*Resolver, error) {
+	resolver := &Resolver{hosts: map[string]string{}}
+	for host, name := range hosts {
+		if _, ok := providers[name]; !ok {
+			return nil, fmt.Errorf("unknown scm provider %q for host %q, must be one of %s", name, host, strings.Join(Names(), ", "))
+		}
+		resolver.hosts[strings.ToLower(host)] = name
+	}
+
+	return resolver, nil
+}
+
+// Provider returns the provider for the repository, see Name for how it is selected
+func (r *Resolver) Provider(repoURL, explicit string) (Provider, error) {
+	name, err := r.Name(repoURL, explicit)
+	if err != nil {
+		return nil, err
+	}
+
+	return providers[name], nil
+}
+
+// Name returns the name of the provider for the repository. An explicit provider name takes precedence,
+// otherwise it is selected by the repository host and defaults to GitHub.
+func (r *Resolver) Name(repoURL, explicit string) (string, error) {
+	if explicit != "" {
+		name := strings.ToLower(explicit)
+		if _, ok := providers[name]; !ok {
+			return "", fmt.Errorf("unknown scm provider %q, must be one of %s", explicit, strings.Join(Names(), ", "))
+		}
+		return name, nil
+	}
+
+	u, err := url.Parse(repoURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse repository url %q: %v", repoURL, err)
+	}
+	host := strings.ToLower(u.Hostname())
+
+	if r != nil {
+		if name, ok := r.hosts[host]; ok {
+			return name, nil
+		}
+	}
+
+	switch {
+	case host == "bitbucket.org":
+		return Bitbucket, nil
+	case strings.Contains(host, "gitlab"):
+		return GitLab, nil
+	case strings.Contains(host, "gitea"):
+		return Gitea, nil
+	case host == "github.com":
+		return GitHub, nil
+	default:
+		// GitHub Enterprise installs share the same url layout as github.com
+		return GitHubEnterprise, nil
+	}
+}
+
+// IsGitHub reports whether the provider name is github.com or GitHub Enterprise
+func IsGitHub(name string) bool {
+	return name == GitHub || name == GitHubEnterprise
+}
+
+// NormaliseRepoURL removes any trailing slash or .git suffix from a repository url, and turns ssh clone urls such as
+// git@github.com:org/app.git or ssh://git@github.com:22/org/app into the web url of the repository
+func NormaliseRepoURL(repoURL string) string {
+	repoURL = strings.TrimSuffix(strings.TrimSuffix(repoURL, "/"), ".git")
+
+	switch {
+	case strings.HasPrefix(repoURL, "ssh://"):
+		u, err := url.Parse(repoURL)
+		if err != nil {
+			return repoURL
+		}
+		// the ssh port is not the port of the web UI
+		return "https://" + u.Hostname() + u.Path
+	case !strings.Contains(repoURL, "://") && strings.Contains(repoURL, ":"):
+		// scp-like syntax, [user@]host:path
+		host, path, _ := strings.Cut(repoURL, ":")
+		host = host[strings.LastIndex(host, "@")+1:]
+		return "https://" + host + "/" + strings.TrimPrefix(path, "/")
+	}
+
+	return repoURL
+}
+
+type github struct{}
+
+func (github) CommitURL(repoURL, sha string) string {
+	return fmt.Sprintf("%s/commit/%s", NormaliseRepoURL(repoURL), sha)
+}
+
+func (github) CompareURL(repoURL, from, to string) string {
+	return fmt.Sprintf("%s/compare/%s...%s", NormaliseRepoURL(repoURL), from, to)
+}
+
+func (github) MergeRequestSearchURL(repoURL, sha string) string {
+	return fmt.Sprintf("%s/pulls/?q=%s", NormaliseRepoURL(repoURL), sha)
+}
+
+func (github) MergeRequestName() string {
+	return "Pull Request"
+}
+
+type gitlab struct{}
+
+func (gitlab) CommitURL(repoURL, sha string) string {
+	return fmt.Sprintf("%s/-/commit/%s", NormaliseRepoURL(repoURL), sha)
+}
+
+func (gitlab) CompareURL(repoURL, from, to string) string {
+	return fmt.Sprintf("%s/-/compare/%s...%s", NormaliseRepoURL(repoURL), from, to)
+}
+
+func (gitlab) MergeRequestSearchURL(repoURL, sha string) string {
+	return fmt.Sprintf("%s/-/merge_requests?scope=all&state=all&search=%s", NormaliseRepoURL(repoURL), sha)
+}
+
+func (gitlab) MergeRequestName() string {
+	return "Merge Request"
+}
+
+type bitbucket struct{}
+
+func (bitbucket) CommitURL(repoURL, sha string) string {
+	return fmt.Sprintf("%s/commits/%s", NormaliseRepoURL(repoURL), sha)
+}
+
+func (bitbucket) CompareURL(repoURL, from, to string) string {
+	// bitbucket compares the source against the destination, separated by a carriage return
+	return fmt.Sprintf("%s/branches/compare/%s%%0D%s", NormaliseRepoURL(repoURL), to, from)
+}
+
+func (bitbucket) MergeRequestSearchURL(repoURL, sha string) string {
+	return fmt.Sprintf("%s/pull-requests/?state=ALL&query=%s", NormaliseRepoURL(repoURL), sha)
+}
+
+func (bitbucket) MergeRequestName() string {
+	return "Pull Request"
+}
+
+type gitea struct{}
+
+func (gitea) CommitURL(repoURL, sha string) string {
+	return fmt.Sprintf("%s/commit/%s", NormaliseRepoURL(repoURL), sha)
+}
+
+func (gitea) CompareURL(repoURL, from, to string) string {
+	return fmt.Sprintf("%s/compare/%s...%s", NormaliseRepoURL(repoURL), from, to)
+}
+
+func (gitea) MergeRequestSearchURL(repoURL, sha string) string {
+	return fmt.Sprintf("%s/pulls?state=all&q=%s", NormaliseRepoURL(repoURL), sha)
+}
+
+func (gitea) MergeRequestName() string {
+	return "Pull Request"
+}
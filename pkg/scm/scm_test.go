@@ -0,0 +1,129 @@
+package scm
+
+import (
+	"testing"
+)
+
+func TestProviderLinks(t *testing.T) {
+	resolver, err := NewResolver(map[string]string{"git.example.com": GitLab})
+	if err != nil {
+		t.Fatalf("NewResolver() error = %v", err)
+	}
+
+	type expected struct {
+		commit       string
+		compare      string
+		mergeRequest string
+	}
+	tests := []struct {
+		name           string
+		repoURL        string
+		explicit       string
+		expectedOutput expected
+	}{
+		{
+			name:    "github",
+			repoURL: "https://github.com/my-org/my-app",
+			expectedOutput: expected{
+				commit:       "https://github.com/my-org/my-app/commit/abc",
+				compare:      "https://github.com/my-org/my-app/compare/old...abc",
+				mergeRequest: "https://github.com/my-org/my-app/pulls/?q=abc",
+			},
+		},
+		{
+			name:    "github enterprise",
+			repoURL: "https://github.example.com/my-org/my-app.git",
+			expectedOutput: expected{
+				commit:       "https://github.example.com/my-org/my-app/commit/abc",
+				compare:      "https://github.example.com/my-org/my-app/compare/old...abc",
+				mergeRequest: "https://github.example.com/my-org/my-app/pulls/?q=abc",
+			},
+		},
+		{
+			name:    "gitlab by host",
+			repoURL: "https://gitlab.com/my-group/my-app/",
+			expectedOutput: expected{
+				commit:       "https://gitlab.com/my-group/my-app/-/commit/abc",
+				compare:      "https://gitlab.com/my-group/my-app/-/compare/old...abc",
+				mergeRequest: "https://gitlab.com/my-group/my-app/-/merge_requests?scope=all&state=all&search=abc",
+			},
+		},
+		{
+			name:    "gitlab by configured host",
+			repoURL: "https://git.example.com/my-group/my-app",
+			expectedOutput: expected{
+				commit:       "https://git.example.com/my-group/my-app/-/commit/abc",
+				compare:      "https://git.example.com/my-group/my-app/-/compare/old...abc",
+				mergeRequest: "https://git.example.com/my-group/my-app/-/merge_requests?scope=all&state=all&search=abc",
+			},
+		},
+		{
+			name:    "bitbucket",
+			repoURL: "https://bitbucket.org/my-team/my-app",
+			expectedOutput: expected{
+				commit:       "https://bitbucket.org/my-team/my-app/commits/abc",
+				compare:      "https://bitbucket.org/my-team/my-app/branches/compare/abc%0Dold",
+				mergeRequest: "https://bitbucket.org/my-team/my-app/pull-requests/?state=ALL&query=abc",
+			},
+		},
+		{
+			name:     "gitea by annotation",
+			repoURL:  "https://code.example.com/my-org/my-app",
+			explicit: "gitea",
+			expectedOutput: expected{
+				commit:       "https://code.example.com/my-org/my-app/commit/abc",
+				compare:      "https://code.example.com/my-org/my-app/compare/old...abc",
+				mergeRequest: "https://code.example.com/my-org/my-app/pulls?state=all&q=abc",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, err := resolver.Provider(tt.repoURL, tt.explicit)
+			if err != nil {
+				t.Fatalf("Provider() error = %v", err)
+			}
+			output := expected{
+				commit:       provider.CommitURL(tt.repoURL, "abc"),
+				compare:      provider.CompareURL(tt.repoURL, "old", "abc"),
+				mergeRequest: provider.MergeRequestSearchURL(tt.repoURL, "abc"),
+			}
+			if output != tt.expectedOutput {
+				t.Errorf("Provider() links = %v, expectedOutput %v", output, tt.expectedOutput)
+			}
+		})
+	}
+}
+
+func TestNormaliseRepoURL(t *testing.T) {
+	tests := []struct {
+		name           string
+		repoURL        string
+		expectedOutput string
+	}{
+		{name: "https", repoURL: "https://github.com/my-org/my-app", expectedOutput: "https://github.com/my-org/my-app"},
+		{name: "git suffix and trailing slash", repoURL: "https://github.com/my-org/my-app.git/", expectedOutput: "https://github.com/my-org/my-app"},
+		{name: "https with a port", repoURL: "https://git.example.com:8443/my-org/my-app", expectedOutput: "https://git.example.com:8443/my-org/my-app"},
+		{name: "scp-like", repoURL: "git@github.com:my-org/my-app.git", expectedOutput: "https://github.com/my-org/my-app"},
+		{name: "scp-like without a user", repoURL: "gitlab.example.com:payments/api", expectedOutput: "https://gitlab.example.com/payments/api"},
+		{name: "ssh", repoURL: "ssh://git@gitlab.example.com/payments/api", expectedOutput: "https://gitlab.example.com/payments/api"},
+		{name: "ssh with a port", repoURL: "ssh://git@git.example.com:22/my-org/my-app.git", expectedOutput: "https://git.example.com/my-org/my-app"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if output := NormaliseRepoURL(tt.repoURL); output != tt.expectedOutput {
+				t.Errorf("NormaliseRepoURL() = %v, expectedOutput %v", output, tt.expectedOutput)
+			}
+		})
+	}
+}
+
+func TestUnknownProvider(t *testing.T) {
+	if _, err := NewResolver(map[string]string{"git.example.com": "svn"}); err == nil {
+		t.Errorf("NewResolver() expected an error for an unknown provider")
+	}
+
+	if _, err := (&Resolver{}).Provider("https://github.com/my-org/my-app", "svn"); err == nil {
+		t.Errorf("Provider() expected an error for an unknown provider")
+	}
+}
@@ -0,0 +1,68 @@
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/slack-go/slack"
+)
+
+const commandUsage = "Usage: `/hermod status <namespace>/<deployment>`"
+
+// HandleCommand answers a `/hermod` slash command, the reply is only shown to the user who ran it
+func (i *Interactions) HandleCommand(ctx context.Context, command slack.SlashCommand) *slack.Msg {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	reply := func(text string) *slack.Msg {
+		return &slack.Msg{ResponseType: slack.ResponseTypeEphemeral, Text: text}
+	}
+
+	args := strings.Fields(command.Text)
+	if len(args) != 2 || args[0] != "status" {
+		return reply(commandUsage)
+	}
+
+	namespace, name, err := parseDeploymentRef(args[1])
+	if err != nil {
+		return reply(fmt.Sprintf("%s\n%s", err, commandUsage))
+	}
+
+	log.Infof("slack user %s (%s) requested the status of `%s/%s`", command.UserID, command.UserName, namespace, name)
+
+	status, err := i.controller.DeploymentStatus(ctx, namespace, name)
+	if err != nil {
+		return reply(fmt.Sprintf(":warning: Could not get the status of `%s/%s`: %v", namespace, name, err))
+	}
+
+	return reply(status)
+}
+
+func (i *Interactions) serveCommand(secret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := verifyRequest(r, secret)
+		if err != nil {
+			log.Warnf("rejected slack command: %v", err)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		command, err := slack.SlashCommandParse(r)
+		if err != nil {
+			log.Warnf("failed to parse slack command: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(i.HandleCommand(r.Context(), command))
+	}
+}
@@ -0,0 +1,227 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	log "github.com/sirupsen/logrus"
+	"github.com/slack-go/slack"
+)
+
+// action ids of the buttons on failure messages
+const (
+	ActionRollback    = "hermod_rollback"
+	ActionPause       = "hermod_pause"
+	ActionAcknowledge = "hermod_acknowledge"
+
+	actionsBlockID = "hermod_actions"
+
+	// section blocks reject text longer than this
+	sectionTextLimit = 3000
+
+	signingSecretEnv = "SLACK_SIGNING_SECRET"
+)
+
+// DeploymentController performs the actions requested from Slack on deployments
+type DeploymentController interface {
+	// AllowedSlackUsers lists the Slack user ids allowed to act on deployments in the namespace
+	AllowedSlackUsers(namespace string) ([]string, error)
+	// RollbackDeployment rolls the deployment back to its last successful revision and describes what it rolled back to
+	RollbackDeployment(ctx context.Context, namespace, name string) (string, error)
+	// PauseDeployment pauses the rollout of the deployment
+	PauseDeployment(ctx context.Context, namespace, name string) error
+	// DeploymentStatus describes the current rollout state of the deployment
+	DeploymentStatus(ctx context.Context, namespace, name string) (string, error)
+}
+
+// Interactions handles the buttons on Hermod messages and the `/hermod` slash command
+type Interactions struct {
+	client     *Client
+	controller DeploymentController
+}
+
+// NewInteractions instantiates the expected Interactions struct fields
+func NewInteractions(client *Client, controller DeploymentController) *Interactions {
+	return &Interactions{client: client, controller: controller}
+}
+
+// HTTPHandler serves the Slack interactivity and slash command endpoints, verifying requests with the SLACK_SIGNING_SECRET
+func (i *Interactions) HTTPHandler() (http.Handler, error) {
+	secret := os.Getenv(signingSecretEnv)
+	if secret == "" {
+		return nil, fmt.Errorf("%s environment variable not set", signingSecretEnv)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slack/interactions", func(w http.ResponseWriter, r *http.Request) {
+		body, err := verifyRequest(r, secret)
+		if err != nil {
+			log.Warnf("rejected slack interaction: %v", err)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var callback slack.InteractionCallback
+		if err := json.Unmarshal([]byte(values.Get("payload")), &callback); err != nil {
+			log.Warnf("failed to parse slack interaction: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		// slack expects a response within 3 seconds, the message is updated once the action is done
+		go i.HandleInteraction(context.Background(), callback)
+
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/slack/commands", i.serveCommand(secret))
+
+	return mux, nil
+}
+
+// HandleInteraction performs the action of a clicked button and updates the message with who did what
+func (i *Interactions) HandleInteraction(ctx context.Context, callback slack.InteractionCallback) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	if callback.Type != slack.InteractionTypeBlockActions {
+		log.Debugf("ignoring slack interaction of type '%s'", callback.Type)
+		return
+	}
+
+	for _, action := range callback.ActionCallback.BlockActions {
+		namespace, name, err := parseDeploymentRef(action.Value)
+		if err != nil {
+			log.Warnf("ignoring slack action '%s': %v", action.ActionID, err)
+			continue
+		}
+
+		allowed, err := i.isAllowed(namespace, callback.User.ID)
+		if err != nil {
+			i.reportError(callback, fmt.Sprintf("failed to check permissions for `%s/%s`: %v", namespace, name, err))
+			continue
+		}
+		if !allowed {
+			log.Infof("slack user %s (%s) is not allowed to act on deployments in `%s` namespace", callback.User.ID, callback.User.Name, namespace)
+			i.replyEphemeral(callback, fmt.Sprintf("You are not allowed to act on deployments in the `%s` namespace.", namespace))
+			continue
+		}
+
+		var outcome string
+		switch action.ActionID {
+		case ActionRollback:
+			revision, err := i.controller.RollbackDeployment(ctx, namespace, name)
+			if err != nil {
+				i.reportError(callback, fmt.Sprintf("failed to roll back `%s/%s`: %v", namespace, name, err))
+				continue
+			}
+			outcome = fmt.Sprintf(":rewind: <@%s> rolled back Deployment `%s` to %s", callback.User.ID, name, revision)
+		case ActionPause:
+			if err := i.controller.PauseDeployment(ctx, namespace, name); err != nil {
+				i.reportError(callback, fmt.Sprintf("failed to pause `%s/%s`: %v", namespace, name, err))
+				continue
+			}
+			outcome = fmt.Sprintf(":double_vertical_bar: <@%s> paused the rollout of Deployment `%s`", callback.User.ID, name)
+		case ActionAcknowledge:
+			outcome = fmt.Sprintf(":eyes: <@%s> acknowledged", callback.User.ID)
+		default:
+			log.Debugf("ignoring unknown slack action '%s'", action.ActionID)
+			continue
+		}
+
+		log.Infof("%s (%s/%s)", outcome, namespace, name)
+		i.updateMessage(callback, outcome)
+	}
+}
+
+func (i *Interactions) isAllowed(namespace, userID string) (bool, error) {
+	users, err := i.controller.AllowedSlackUsers(namespace)
+	if err != nil {
+		return false, err
+	}
+
+	for _, user := range users {
+		if user == userID {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// updateMessage replaces the buttons of the original message with the outcome of the action
+func (i *Interactions) updateMessage(callback slack.InteractionCallback, outcome string) {
+	attachments := callback.Message.Attachments
+	for a := range attachments {
+		var blocks []slack.Block
+		for _, block := range attachments[a].Blocks.BlockSet {
+			if block.BlockType() == slack.MBTAction && block.(*slack.ActionBlock).BlockID == actionsBlockID {
+				continue
+			}
+			blocks = append(blocks, block)
+		}
+		blocks = append(blocks, slack.NewContextBlock("", slack.NewTextBlockObject(slack.MarkdownType, outcome, false, false)))
+		attachments[a].Blocks = slack.Blocks{BlockSet: blocks}
+	}
+
+	_, _, _, err := i.client.client.UpdateMessage(callback.Channel.ID, callback.Message.Timestamp, slack.MsgOptionAttachments(attachments...))
+	if err != nil {
+		message := fmt.Sprintf("failed to update slack message: %v", err)
+		log.Error(message)
+		sentry.CaptureMessage(message)
+	}
+}
+
+func (i *Interactions) replyEphemeral(callback slack.InteractionCallback, message string) {
+	_, err := i.client.client.PostEphemeral(callback.Channel.ID, callback.User.ID, slack.MsgOptionText(message, false))
+	if err != nil {
+		log.Errorf("failed to send ephemeral slack message: %v", err)
+	}
+}
+
+func (i *Interactions) reportError(callback slack.InteractionCallback, message string) {
+	log.Error(message)
+	sentry.CaptureMessage(message)
+	i.replyEphemeral(callback, ":warning: "+message)
+}
+
+// parseDeploymentRef splits a `namespace/name` reference to a deployment
+func parseDeploymentRef(ref string) (string, string, error) {
+	parts := strings.Split(ref, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("%q is not in the form <namespace>/<deployment>", ref)
+	}
+	return parts[0], parts[1], nil
+}
+
+// verifyRequest checks the Slack signature of a request and returns its body
+func verifyRequest(r *http.Request, secret string) ([]byte, error) {
+	verifier, err := slack.NewSecretsVerifier(r.Header, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(io.TeeReader(io.LimitReader(r.Body, 1<<20), &verifier))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verifier.Ensure(); err != nil {
+		return nil, err
+	}
+
+	return body, nil
+}
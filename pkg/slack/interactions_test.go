@@ -0,0 +1,167 @@
+package slack
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+func signedRequest(secret, body string, timestamp time.Time) *http.Request {
+	r, _ := http.NewRequest(http.MethodPost, "/slack/interactions", strings.NewReader(body))
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(fmt.Sprintf("v0:%s:%s", ts, body)))
+
+	r.Header.Set("X-Slack-Request-Timestamp", ts)
+	r.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	return r
+}
+
+func TestVerifyRequest(t *testing.T) {
+	tests := []struct {
+		name        string
+		request     *http.Request
+		expectError bool
+	}{
+		{
+			name:    "valid signature",
+			request: signedRequest("secret", "payload=%7B%7D", time.Now()),
+		},
+		{
+			name:        "wrong secret",
+			request:     signedRequest("other", "payload=%7B%7D", time.Now()),
+			expectError: true,
+		},
+		{
+			name:        "replayed request",
+			request:     signedRequest("secret", "payload=%7B%7D", time.Now().Add(-time.Hour)),
+			expectError: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := verifyRequest(tt.request, "secret")
+			if (err != nil) != tt.expectError {
+				t.Fatalf("verifyRequest() error = %v, expectError %v", err, tt.expectError)
+			}
+			if err == nil && string(body) != "payload=%7B%7D" {
+				t.Errorf("verifyRequest() = %v, expectedOutput %v", string(body), "payload=%7B%7D")
+			}
+		})
+	}
+}
+
+func TestParseDeploymentRef(t *testing.T) {
+	tests := []struct {
+		name              string
+		ref               string
+		expectedNamespace string
+		expectedName      string
+		expectError       bool
+	}{
+		{
+			name:              "namespace and name",
+			ref:               "payments/api",
+			expectedNamespace: "payments",
+			expectedName:      "api",
+		},
+		{
+			name:        "missing namespace",
+			ref:         "api",
+			expectError: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			namespace, name, err := parseDeploymentRef(tt.ref)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("parseDeploymentRef() error = %v, expectError %v", err, tt.expectError)
+			}
+			if namespace != tt.expectedNamespace || name != tt.expectedName {
+				t.Errorf("parseDeploymentRef() = %v, %v, expectedOutput %v, %v", namespace, name, tt.expectedNamespace, tt.expectedName)
+			}
+		})
+	}
+}
+
+func TestMessageAttachment(t *testing.T) {
+	message := Message{
+		Header:  "Failed to roll out api",
+		Summary: "*Rollout failed.*",
+		Context: []string{"*Namespace:* `payments`"},
+		Fields:  []Field{{Name: "Replicas", Value: "`0/1` ready"}},
+		Errors:  []Error{{Reason: "ImagePullBackOff", Message: strings.Repeat("x", errorTextLimit+10)}},
+		Links:   []Link{{Text: "Commit", URL: "https://github.com/uswitch/api/commit/abc"}},
+		Actions: "payments/api",
+		Color:   RedColor,
+	}
+
+	attachment := message.attachment()
+	if attachment.Color != RedColor || attachment.Fallback != message.Summary {
+		t.Errorf("attachment() color, fallback = %v, %v, expectedOutput %v, %v", attachment.Color, attachment.Fallback, RedColor, message.Summary)
+	}
+
+	var types []slack.MessageBlockType
+	for _, block := range attachment.Blocks.BlockSet {
+		types = append(types, block.BlockType())
+	}
+	expectedTypes := []slack.MessageBlockType{slack.MBTHeader, slack.MBTContext, slack.MBTSection, slack.MBTSection, slack.MBTSection, slack.MBTAction, slack.MBTAction}
+	if fmt.Sprint(types) != fmt.Sprint(expectedTypes) {
+		t.Fatalf("attachment() blocks = %v, expectedOutput %v", types, expectedTypes)
+	}
+
+	errorText := attachment.Blocks.BlockSet[4].(*slack.SectionBlock).Text.Text
+	if !strings.HasPrefix(errorText, "*ImagePullBackOff*\n```") || !strings.HasSuffix(errorText, "...```") {
+		t.Errorf("attachment() error section = %v, expected a truncated ImagePullBackOff section", errorText)
+	}
+
+	link := attachment.Blocks.BlockSet[5].(*slack.ActionBlock).Elements.ElementSet[0].(*slack.ButtonBlockElement)
+	if link.URL != message.Links[0].URL {
+		t.Errorf("attachment() link = %v, expectedOutput %v", link.URL, message.Links[0].URL)
+	}
+}
+
+func TestMention(t *testing.T) {
+	tests := []struct {
+		name           string
+		id             string
+		expectedOutput string
+		expectError    bool
+	}{
+		{
+			name:           "user",
+			id:             "U012AB3CD",
+			expectedOutput: "<@U012AB3CD>",
+		},
+		{
+			name:           "user group",
+			id:             "S012AB3CD",
+			expectedOutput: "<!subteam^S012AB3CD>",
+		},
+		{
+			name:        "channel",
+			id:          "C012AB3CD",
+			expectError: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := mention(tt.id)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("mention() error = %v, expectError %v", err, tt.expectError)
+			}
+			if output != tt.expectedOutput {
+				t.Errorf("mention() = %v, expectedOutput %v", output, tt.expectedOutput)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package slack
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Mentions turns Slack user ids, user group ids and emails into mentions, emails are looked up with users.lookupByEmail.
+// Owners that cannot be mentioned are left out and reported in the error.
+func (c *Client) Mentions(owners []string) ([]string, error) {
+	var mentions, failed []string
+	for _, owner := range owners {
+		if !strings.Contains(owner, "@") {
+			mention, err := mention(owner)
+			if err != nil {
+				failed = append(failed, err.Error())
+				continue
+			}
+			mentions = append(mentions, mention)
+			continue
+		}
+
+		user, err := c.client.GetUserByEmail(owner)
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("failed to look up %s: %v", owner, err))
+			continue
+		}
+		mentions = append(mentions, fmt.Sprintf("<@%s>", user.ID))
+	}
+
+	if len(failed) > 0 {
+		return mentions, fmt.Errorf("%s", strings.Join(failed, ", "))
+	}
+
+	return mentions, nil
+}
+
+// mention formats a Slack user or user group id as a mention
+func mention(id string) (string, error) {
+	switch {
+	case strings.HasPrefix(id, "U") || strings.HasPrefix(id, "W"):
+		return fmt.Sprintf("<@%s>", id), nil
+	case strings.HasPrefix(id, "S"):
+		return fmt.Sprintf("<!subteam^%s>", id), nil
+	}
+
+	return "", fmt.Errorf("%q is not a Slack user id, user group id or email", id)
+}
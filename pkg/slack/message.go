@@ -0,0 +1,173 @@
+package slack
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/slack-go/slack"
+	"github.com/uswitch/hermod/pkg/text"
+)
+
+const (
+	linksBlockID = "hermod_links"
+
+	// header blocks reject text longer than this
+	headerTextLimit = 150
+	// Slack cannot collapse blocks, so long error messages are cut to this length
+	errorTextLimit = 500
+	// context blocks take at most this many elements
+	contextElementLimit = 10
+	// section blocks take at most this many fields
+	sectionFieldLimit = 10
+)
+
+// Message is a notification about a deployment, laid out with Block Kit in an attachment coloured by the outcome
+type Message struct {
+	// Header is the plain text title of the message
+	Header string
+	// Summary is the markdown sentence describing what happened, also used in notifications
+	Summary string
+	// Context is shown in small print under the header, e.g. the cluster, namespace and revision
+	Context []string
+	// Fields are shown side by side, e.g. the ready replicas and duration of the rollout
+	Fields []Field
+	// Sections are further markdown sections, e.g. the changes in the rollout
+	Sections []string
+	// Errors are shown in a section per reason
+	Errors []Error
+	// Links are shown as buttons, e.g. to the commit or pull request
+	Links []Link
+	// Actions is the `namespace/name` of the deployment to offer roll back, pause and acknowledge buttons for
+	Actions string
+	// Color is one of OrangeColor, GreenColor or RedColor
+	Color string
+	// Mentions are sent as the text of the message, Slack does not notify mentions within attachments
+	Mentions []string
+}
+
+// Field is a labelled value of a message
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Error is an error found during a rollout
+type Error struct {
+	Reason  string
+	Message string
+}
+
+// Link is a button opening a URL
+type Link struct {
+	Text string
+	URL  string
+}
+
+// attachment renders the message as blocks in an attachment so the colour bar is kept
+func (m Message) attachment() slack.Attachment {
+	var blocks []slack.Block
+
+	if m.Header != "" {
+		blocks = append(blocks, slack.NewHeaderBlock(plainText(text.Truncate(m.Header, headerTextLimit))))
+	}
+
+	if len(m.Context) > 0 {
+		var elements []slack.MixedElement
+		for _, context := range m.Context {
+			if len(elements) == contextElementLimit {
+				break
+			}
+			elements = append(elements, markdownText(context))
+		}
+		blocks = append(blocks, slack.NewContextBlock("", elements...))
+	}
+
+	if m.Summary != "" {
+		blocks = append(blocks, slack.NewSectionBlock(markdownText(text.Truncate(m.Summary, sectionTextLimit)), nil, nil))
+	}
+
+	if len(m.Fields) > 0 {
+		var fields []*slack.TextBlockObject
+		for _, field := range m.Fields {
+			if len(fields) == sectionFieldLimit {
+				break
+			}
+			fields = append(fields, markdownText(fmt.Sprintf("*%s:*\n%s", field.Name, field.Value)))
+		}
+		blocks = append(blocks, slack.NewSectionBlock(nil, fields, nil))
+	}
+
+	for _, e := range m.Errors {
+		errorText := fmt.Sprintf("```%s```", text.Truncate(e.Message, errorTextLimit))
+		if e.Reason != "" {
+			errorText = fmt.Sprintf("*%s*\n%s", e.Reason, errorText)
+		}
+		blocks = append(blocks, slack.NewSectionBlock(markdownText(errorText), nil, nil))
+	}
+
+	for _, section := range m.Sections {
+		blocks = append(blocks, slack.NewSectionBlock(markdownText(text.Truncate(section, sectionTextLimit)), nil, nil))
+	}
+
+	if len(m.Links) > 0 {
+		var buttons []slack.BlockElement
+		for n, link := range m.Links {
+			button := slack.NewButtonBlockElement(fmt.Sprintf("%s_%d", linksBlockID, n), "", plainText(link.Text))
+			button.URL = link.URL
+			buttons = append(buttons, button)
+		}
+		blocks = append(blocks, slack.NewActionBlock(linksBlockID, buttons...))
+	}
+
+	if m.Actions != "" {
+		blocks = append(blocks, actionsBlock(m.Actions))
+	}
+
+	fallback := m.Summary
+	if fallback == "" {
+		fallback = m.Header
+	}
+
+	return slack.Attachment{
+		Color:    m.Color,
+		Fallback: fallback,
+		Blocks:   slack.Blocks{BlockSet: blocks},
+	}
+}
+
+// options are the options to post or update the message with
+func (m Message) options() []slack.MsgOption {
+	options := []slack.MsgOption{slack.MsgOptionAttachments(m.attachment())}
+	if len(m.Mentions) > 0 {
+		options = append(options, slack.MsgOptionText(strings.Join(m.Mentions, " "), false))
+	}
+
+	return options
+}
+
+// actionsBlock offers buttons to roll back, pause or acknowledge the deployment referenced as `namespace/name`
+func actionsBlock(ref string) *slack.ActionBlock {
+	deployment := ref[strings.Index(ref, "/")+1:]
+
+	rollback := slack.NewButtonBlockElement(ActionRollback, ref, plainText("Roll back"))
+	rollback.Style = slack.StyleDanger
+	rollback.Confirm = slack.NewConfirmationBlockObject(
+		plainText("Roll back?"),
+		markdownText(fmt.Sprintf("Roll back Deployment `%s` to its last successful revision?", deployment)),
+		plainText("Roll back"),
+		plainText("Cancel"),
+	)
+	pause := slack.NewButtonBlockElement(ActionPause, ref, plainText("Pause rollout"))
+	acknowledge := slack.NewButtonBlockElement(ActionAcknowledge, ref, plainText("Acknowledge"))
+	acknowledge.Style = slack.StylePrimary
+
+	return slack.NewActionBlock(actionsBlockID, rollback, pause, acknowledge)
+}
+
+func plainText(text string) *slack.TextBlockObject {
+	return slack.NewTextBlockObject(slack.PlainTextType, text, false, false)
+}
+
+func markdownText(text string) *slack.TextBlockObject {
+	return slack.NewTextBlockObject(slack.MarkdownType, text, false, false)
+}
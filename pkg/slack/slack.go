@@ -27,35 +27,53 @@ func NewClient() (*Client, error) {
 		return nil, fmt.Errorf("SLACK_TOKEN environment variable not set")
 	}
 
-	return &Client{client: slack.New(token)}, nil
+	// the app-level token is only needed to connect in socket mode
+	return &Client{client: slack.New(token, slack.OptionAppLevelToken(os.Getenv(appTokenEnv)))}, nil
 }
 
-func (c *Client) SendMessage(channel, message, color string) error {
-	log.Debugf("sending alert \"%s\" to '%s'", message, channel)
+// SendMessage posts the message to the channel
+func (c *Client) SendMessage(channel string, message Message) error {
+	_, err := c.PostMessage(channel, message)
+	return err
+}
 
-	_, _, err := c.client.PostMessage(channel, slack.MsgOptionAttachments(createSlackAttachment(message, color)))
+// MessageRef identifies a posted message so it can be updated
+type MessageRef struct {
+	Channel   string
+	Timestamp string
+}
 
+// PostMessage posts the message to the channel and returns where it was posted
+func (c *Client) PostMessage(channel string, message Message) (MessageRef, error) {
+	log.Debugf("sending alert \"%s\" to '%s'", message.Summary, channel)
+
+	channelID, timestamp, err := c.client.PostMessage(channel, message.options()...)
 	if err != nil {
-		return fmt.Errorf("failed to send message \"%s\" to '%s': %s", message, channel, err)
+		return MessageRef{}, fmt.Errorf("failed to send message \"%s\" to '%s': %s", message.Summary, channel, err)
 	}
 
-	return err
+	return MessageRef{Channel: channelID, Timestamp: timestamp}, nil
 }
 
-func createSlackAttachment(msg, color string) slack.Attachment {
+// UpdateMessage replaces a posted message
+func (c *Client) UpdateMessage(ref MessageRef, message Message) error {
+	log.Debugf("updating alert \"%s\" in '%s'", message.Summary, ref.Channel)
 
-	attachment := slack.Attachment{
-		Footer: "hermod",
-		Fields: []slack.AttachmentField{
-			{
-				Value: msg,
-			},
-		},
+	_, _, _, err := c.client.UpdateMessage(ref.Channel, ref.Timestamp, message.options()...)
+	if err != nil {
+		return fmt.Errorf("failed to update message \"%s\" in '%s': %s", message.Summary, ref.Channel, err)
 	}
 
-	attachment.Color = color
+	return nil
+}
 
-	attachment.MarkdownIn = []string{"fields"}
+// SendDirectMessage posts the message to the Slack user with the given email
+func (c *Client) SendDirectMessage(email string, message Message) error {
+	user, err := c.client.GetUserByEmail(email)
+	if err != nil {
+		return fmt.Errorf("failed to look up %s: %v", email, err)
+	}
 
-	return attachment
+	// posting to a user id sends the message to their direct messages with the app
+	return c.SendMessage(user.ID, message)
 }
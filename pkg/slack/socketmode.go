@@ -0,0 +1,63 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/getsentry/sentry-go"
+	log "github.com/sirupsen/logrus"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/socketmode"
+)
+
+const appTokenEnv = "SLACK_APP_TOKEN"
+
+// StartSocketMode receives button clicks and slash commands over a Socket Mode websocket instead of
+// the HTTP endpoints, so Hermod needs no public ingress. The connection is kept until the context is done.
+func (i *Interactions) StartSocketMode(ctx context.Context) error {
+	if os.Getenv(appTokenEnv) == "" {
+		return fmt.Errorf("%s environment variable not set", appTokenEnv)
+	}
+
+	client := socketmode.New(i.client.client)
+
+	go func() {
+		for event := range client.Events {
+			switch event.Type {
+			case socketmode.EventTypeConnecting:
+				log.Info("connecting to slack in socket mode")
+			case socketmode.EventTypeConnected:
+				log.Info("connected to slack in socket mode")
+			case socketmode.EventTypeConnectionError, socketmode.EventTypeInvalidAuth:
+				log.Errorf("slack socket mode connection error: %v", event.Data)
+			case socketmode.EventTypeInteractive:
+				callback, ok := event.Data.(slack.InteractionCallback)
+				if !ok {
+					continue
+				}
+				client.Ack(*event.Request)
+				go i.HandleInteraction(ctx, callback)
+			case socketmode.EventTypeSlashCommand:
+				command, ok := event.Data.(slack.SlashCommand)
+				if !ok {
+					continue
+				}
+				client.Ack(*event.Request, i.HandleCommand(ctx, command))
+			default:
+				log.Debugf("ignoring slack socket mode event of type '%s'", event.Type)
+			}
+		}
+	}()
+
+	go func() {
+		err := client.RunContext(ctx)
+		if err != nil && ctx.Err() == nil {
+			message := fmt.Sprintf("slack socket mode stopped: %v", err)
+			log.Error(message)
+			sentry.CaptureMessage(message)
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,304 @@
+package templates
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// sinks messages are sent to
+const (
+	SinkSlack  = "slack"
+	SinkGithub = "github"
+)
+
+// phases of a rollout
+const (
+	PhaseStarted   = "started"
+	PhaseSucceeded = "succeeded"
+	PhaseFailed    = "failed"
+	// PhaseAutoRollback is the outcome of an automatic rollback of a failed rollout
+	PhaseAutoRollback = "auto-rollback"
+	// PhaseEscalated is a failed rollout still failing after the escalation delay
+	PhaseEscalated = "escalated"
+)
+
+// the header of a Slack message is templated as `slack-<phase>-header`
+const headerSuffix = "-header"
+
+var (
+	sinks = []string{SinkSlack, SinkGithub}
+	// automatic rollbacks and escalations are only sent to Slack
+	phases = map[string][]string{
+		SinkSlack:  {PhaseStarted, PhaseSucceeded, PhaseFailed, PhaseAutoRollback, PhaseEscalated},
+		SinkGithub: {PhaseStarted, PhaseSucceeded, PhaseFailed},
+	}
+)
+
+// Event is the rollout a message is rendered for
+type Event struct {
+	Phase         string
+	Cluster       string
+	Namespace     string
+	Name          string
+	Revision      string
+	Labels        map[string]string
+	Annotations   map[string]string
+	Images        []string
+	Replicas      int32
+	ReadyReplicas int32
+	// Duration of the rollout, only set once it finished
+	Duration string
+	// RolledBackTo describes the revision a rollout went back to, only set for rollbacks
+	RolledBackTo string
+	Errors       []Error
+	Git          Git
+	Helm         Helm
+	// AutoRollback is only set for the auto-rollback phase
+	AutoRollback AutoRollback
+	// EscalatedAfter is the escalation delay, only set for the escalated phase
+	EscalatedAfter string
+}
+
+// Error is an error found during a rollout
+type Error struct {
+	Reason  string
+	Message string
+}
+
+// Git describes the commit being rolled out, it is empty when the deployment has no git annotations
+type Git struct {
+	Repo           string
+	SHA            string
+	CommitURL      string
+	PullRequestURL string
+}
+
+// Helm describes the Helm release being rolled out, it is empty when the deployment was not installed by Helm
+type Helm struct {
+	Release      string
+	Revision     int
+	Chart        string
+	ChartVersion string
+	AppVersion   string
+}
+
+// AutoRollback describes the automatic rollback of a failed rollout
+type AutoRollback struct {
+	RolledBack bool
+	// Revision the deployment was rolled back to
+	Revision string
+	// Reason the rollout failed, e.g. ProgressDeadlineExceeded
+	Reason string
+	// NotRolledBack explains why the deployment was not rolled back
+	NotRolledBack string
+}
+
+// Templates renders messages for each sink and phase, messages without a template keep the default wording
+type Templates struct {
+	templates *template.Template
+}
+
+// Names lists the templates that can be defined, `<sink>-<phase>` and `slack-<phase>-header`
+func Names() []string {
+	var names []string
+	for name := range templatePhases() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// templatePhases maps the name of each template that can be defined to the phase it is rendered for
+func templatePhases() map[string]string {
+	names := map[string]string{}
+	for _, sink := range sinks {
+		for _, phase := range phases[sink] {
+			names[name(sink, phase)] = phase
+			if sink == SinkSlack {
+				names[name(sink, phase)+headerSuffix] = phase
+			}
+		}
+	}
+	return names
+}
+
+func name(sink, phase string) string {
+	return fmt.Sprintf("%s-%s", sink, phase)
+}
+
+var funcs = template.FuncMap{
+	"join":  strings.Join,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"short": func(sha string) string {
+		if len(sha) > 7 {
+			return sha[:7]
+		}
+		return sha
+	},
+}
+
+// Load reads templates from a file or a directory, such as a mounted ConfigMap.
+// Each file in a directory is a template named after the file without its extension, e.g. `slack-failed.tmpl`.
+// A single file declares its templates with `{{define "slack-failed"}}...{{end}}`.
+func Load(path string) (*Templates, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read templates: %v", err)
+	}
+
+	if !info.IsDir() {
+		text, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read templates: %v", err)
+		}
+		return Parse(map[string]string{"_" + filepath.Base(path): string(text)})
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read templates: %v", err)
+	}
+
+	files := map[string]string{}
+	for _, entry := range entries {
+		// skip the hidden directories kubernetes mounts ConfigMaps with
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		file := filepath.Join(path, entry.Name())
+		if info, err := os.Stat(file); err != nil || info.IsDir() {
+			continue
+		}
+
+		text, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template %s: %v", file, err)
+		}
+		files[entry.Name()] = string(text)
+	}
+
+	return Parse(files)
+}
+
+// LoadConfigMap reads templates from the keys of a ConfigMap, named like the files of a directory
+func LoadConfigMap(ctx context.Context, client kubernetes.Interface, namespace, name string) (*Templates, error) {
+	configMap, err := client.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get templates ConfigMap %s/%s: %v", namespace, name, err)
+	}
+
+	return Parse(configMap.Data)
+}
+
+// Parse parses the templates keyed by file name and checks they render.
+// Templates whose name starts with `_` can hold shared definitions, any other name must be one of Names.
+func Parse(files map[string]string) (*Templates, error) {
+	var fileNames []string
+	for fileName := range files {
+		fileNames = append(fileNames, fileName)
+	}
+	sort.Strings(fileNames)
+
+	root := template.New("").Funcs(funcs)
+	for _, fileName := range fileNames {
+		templateName := strings.TrimSuffix(fileName, filepath.Ext(fileName))
+		if _, err := root.New(templateName).Parse(files[fileName]); err != nil {
+			return nil, fmt.Errorf("failed to parse template %s: %v", fileName, err)
+		}
+	}
+
+	known := templatePhases()
+	for _, t := range root.Templates() {
+		if t.Name() == "" || strings.HasPrefix(t.Name(), "_") {
+			continue
+		}
+		phase, ok := known[t.Name()]
+		if !ok {
+			return nil, fmt.Errorf("unknown template %q, expected one of %s", t.Name(), strings.Join(Names(), ", "))
+		}
+
+		if err := t.Execute(&bytes.Buffer{}, sampleEvent(phase)); err != nil {
+			return nil, fmt.Errorf("template %q does not render: %v", t.Name(), err)
+		}
+	}
+
+	return &Templates{templates: root}, nil
+}
+
+// Render renders the template of the sink for the phase of the event, it returns false when there is no such template
+func (t *Templates) Render(sink string, event Event) (string, bool, error) {
+	return t.render(name(sink, event.Phase), event)
+}
+
+// RenderHeader renders the header template of the sink for the phase of the event, only Slack messages have a header
+func (t *Templates) RenderHeader(sink string, event Event) (string, bool, error) {
+	return t.render(name(sink, event.Phase)+headerSuffix, event)
+}
+
+func (t *Templates) render(name string, event Event) (string, bool, error) {
+	if t == nil {
+		return "", false, nil
+	}
+
+	tmpl := t.templates.Lookup(name)
+	if tmpl == nil || tmpl.Tree == nil {
+		return "", false, nil
+	}
+
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, event); err != nil {
+		return "", false, fmt.Errorf("failed to render template %q: %v", tmpl.Name(), err)
+	}
+
+	return strings.TrimSpace(out.String()), true, nil
+}
+
+// sampleEvent is a rollout with every field set, templates are checked against it at startup
+func sampleEvent(phase string) Event {
+	return Event{
+		Phase:         phase,
+		Cluster:       "cluster",
+		Namespace:     "namespace",
+		Name:          "deployment",
+		Revision:      "2",
+		Labels:        map[string]string{"app": "deployment"},
+		Annotations:   map[string]string{"deployment.kubernetes.io/revision": "2"},
+		Images:        []string{"registry/app:v2"},
+		Replicas:      2,
+		ReadyReplicas: 1,
+		Duration:      "1m30s",
+		RolledBackTo:  "revision 1 (image `registry/app:v1`)",
+		Errors:        []Error{{Reason: "ImagePullBackOff", Message: "Back-off pulling image"}},
+		Git: Git{
+			Repo:           "https://github.com/org/app",
+			SHA:            "2dafeb708437f6e537d19556d461e30aa96d4244",
+			CommitURL:      "https://github.com/org/app/commit/2dafeb708437f6e537d19556d461e30aa96d4244",
+			PullRequestURL: "https://github.com/org/app/pulls?q=2dafeb708437f6e537d19556d461e30aa96d4244",
+		},
+		Helm: Helm{
+			Release:      "app",
+			Revision:     3,
+			Chart:        "app",
+			ChartVersion: "1.2.0",
+			AppVersion:   "v2",
+		},
+		AutoRollback: AutoRollback{
+			RolledBack:    true,
+			Revision:      "1",
+			Reason:        "ProgressDeadlineExceeded",
+			NotRolledBack: "no revision has been recorded as successful yet.",
+		},
+		EscalatedAfter: "30m0s",
+	}
+}
@@ -0,0 +1,173 @@
+package templates
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name        string
+		files       map[string]string
+		expectError bool
+	}{
+		{
+			name:  "template per file",
+			files: map[string]string{"slack-failed.tmpl": "{{ .Name }} failed: {{ range .Errors }}{{ .Reason }} {{ end }}"},
+		},
+		{
+			name:  "shared definitions",
+			files: map[string]string{"_all.tmpl": `{{ define "slack-started" }}{{ .Name }} {{ short .Git.SHA }}{{ end }}`},
+		},
+		{
+			name:  "header and escalation templates",
+			files: map[string]string{"slack-failed-header.tmpl": ":x: {{ .Name }}", "slack-escalated.tmpl": "{{ .Name }} still failing after {{ .EscalatedAfter }}"},
+		},
+		{
+			name:  "automatic rollback template",
+			files: map[string]string{"slack-auto-rollback.tmpl": "{{ if .AutoRollback.RolledBack }}rolled back to {{ .AutoRollback.Revision }}{{ else }}{{ .AutoRollback.NotRolledBack }}{{ end }}"},
+		},
+		{
+			name:        "unknown template",
+			files:       map[string]string{"slack-failure.tmpl": "{{ .Name }}"},
+			expectError: true,
+		},
+		{
+			name:        "phase only sent to slack",
+			files:       map[string]string{"github-escalated.tmpl": "{{ .Name }}"},
+			expectError: true,
+		},
+		{
+			name:        "header of a github message",
+			files:       map[string]string{"github-failed-header.tmpl": "{{ .Name }}"},
+			expectError: true,
+		},
+		{
+			name:        "unknown field",
+			files:       map[string]string{"github-succeeded.tmpl": "{{ .Deployment }}"},
+			expectError: true,
+		},
+		{
+			name:        "syntax error",
+			files:       map[string]string{"slack-succeeded.tmpl": "{{ .Name "},
+			expectError: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.files)
+			if (err != nil) != tt.expectError {
+				t.Errorf("Parse() error = %v, expectError %v", err, tt.expectError)
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	templates, err := Parse(map[string]string{
+		"slack-failed.tmpl":        "{{ .Name }} failed in {{ .Namespace }} ({{ index .Labels \"team\" }})\n",
+		"slack-failed-header.tmpl": ":x: {{ .Name }}",
+	})
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		templates      *Templates
+		sink           string
+		header         bool
+		event          Event
+		expectedOutput string
+		expectedOK     bool
+	}{
+		{
+			name:           "defined template",
+			templates:      templates,
+			sink:           SinkSlack,
+			event:          Event{Phase: PhaseFailed, Name: "api", Namespace: "payments", Labels: map[string]string{"team": "checkout"}},
+			expectedOutput: "api failed in payments (checkout)",
+			expectedOK:     true,
+		},
+		{
+			name:           "defined header template",
+			templates:      templates,
+			sink:           SinkSlack,
+			header:         true,
+			event:          Event{Phase: PhaseFailed, Name: "api"},
+			expectedOutput: ":x: api",
+			expectedOK:     true,
+		},
+		{
+			name:      "no header template for the phase",
+			templates: templates,
+			sink:      SinkSlack,
+			header:    true,
+			event:     Event{Phase: PhaseSucceeded, Name: "api"},
+		},
+		{
+			name:      "no template for the phase",
+			templates: templates,
+			sink:      SinkSlack,
+			event:     Event{Phase: PhaseStarted, Name: "api"},
+		},
+		{
+			name:  "no templates",
+			sink:  SinkGithub,
+			event: Event{Phase: PhaseFailed, Name: "api"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			render := tt.templates.Render
+			if tt.header {
+				render = tt.templates.RenderHeader
+			}
+			output, ok, err := render(tt.sink, tt.event)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if output != tt.expectedOutput || ok != tt.expectedOK {
+				t.Errorf("Render() = %v, %v, expectedOutput %v, %v", output, ok, tt.expectedOutput, tt.expectedOK)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	// lay the directory out like a mounted ConfigMap
+	dir := t.TempDir()
+	data := filepath.Join(dir, "..2021_06_01")
+	os.Mkdir(data, 0755)
+	os.WriteFile(filepath.Join(data, "slack-started.tmpl"), []byte("starting {{ .Name }}"), 0644)
+	os.Symlink(filepath.Join(data, "slack-started.tmpl"), filepath.Join(dir, "slack-started.tmpl"))
+
+	templates, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if output, _, _ := templates.Render(SinkSlack, Event{Phase: PhaseStarted, Name: "api"}); output != "starting api" {
+		t.Errorf("Load() rendered %v, expectedOutput %v", output, "starting api")
+	}
+}
+
+func TestLoadConfigMap(t *testing.T) {
+	client := k8sfake.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "hermod-templates", Namespace: "hermod"},
+		Data:       map[string]string{"github-failed.tmpl": "{{ .Name }} failed"},
+	})
+
+	templates, err := LoadConfigMap(context.Background(), client, "hermod", "hermod-templates")
+	if err != nil {
+		t.Fatalf("LoadConfigMap() error = %v", err)
+	}
+	if output, _, _ := templates.Render(SinkGithub, Event{Phase: PhaseFailed, Name: "api"}); output != "api failed" {
+		t.Errorf("LoadConfigMap() rendered %v, expectedOutput %v", output, "api failed")
+	}
+}
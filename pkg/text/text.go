@@ -0,0 +1,11 @@
+package text
+
+// Truncate shortens s to at most length characters, ending it with "..." when it is cut,
+// to fit the length limits of the Slack and GitHub APIs
+func Truncate(s string, length int) string {
+	runes := []rune(s)
+	if len(runes) <= length {
+		return s
+	}
+	return string(runes[:length-3]) + "..."
+}
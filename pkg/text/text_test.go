@@ -0,0 +1,38 @@
+package text
+
+import "testing"
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name           string
+		input          string
+		length         int
+		expectedOutput string
+	}{
+		{
+			name:           "short enough",
+			input:          "rolled out",
+			length:         10,
+			expectedOutput: "rolled out",
+		},
+		{
+			name:           "too long",
+			input:          "rolled out api",
+			length:         10,
+			expectedOutput: "rolled ...",
+		},
+		{
+			name:           "multibyte characters",
+			input:          "déploiement réussi",
+			length:         10,
+			expectedOutput: "déploie...",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if output := Truncate(tt.input, tt.length); output != tt.expectedOutput {
+				t.Errorf("Truncate() = %q, expectedOutput %q", output, tt.expectedOutput)
+			}
+		})
+	}
+}